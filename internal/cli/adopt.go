@@ -0,0 +1,320 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	jira "github.com/ctreminiom/go-atlassian/jira/v3"
+	"github.com/iolave/jira-tickets-from-gh/internal/helpers"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ADOPT_ACCEPT_SCORE is the score from which candidate pairs written to
+	// a csv are accepted by default.
+	ADOPT_ACCEPT_SCORE = 0.9
+	// ADOPT_SEARCH_PAGE_SIZE is the number of jira issues retrieved per
+	// search request.
+	ADOPT_SEARCH_PAGE_SIZE = 100
+)
+
+type AdoptCmd struct {
+	Config   string  `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project  string  `arg:"required,--project" help:"sync project name" placeholder:"<NAME>"`
+	JQL      *string `arg:"--jql" help:"extra JQL filter for the jira issues to match (i.e. \"created >= -180d\")" placeholder:"<JQL>"`
+	MinScore float64 `arg:"--min-score" default:"0.6" help:"minimum score of a candidate pair, from 0 to 1"`
+	Output   *string `arg:"--output,-o" help:"write the candidate pairs to a csv file for review instead of prompting" placeholder:"<PATH>"`
+	From     *string `arg:"--from" help:"link the accepted pairs of a reviewed csv file instead of prompting" placeholder:"<PATH>"`
+}
+
+// adoptCandidate is a GitHub project item and jira issue pair that are
+// likely the same ticket.
+type adoptCandidate struct {
+	ItemID  string
+	Title   string
+	JiraKey string
+	Summary string
+	Score   float64
+	Accept  bool
+}
+
+// ADOPT_CSV_HEADER are the columns of the candidate pairs csv files.
+var ADOPT_CSV_HEADER = []string{"itemId", "title", "jiraKey", "summary", "score", "accept"}
+
+// AdoptAction matches the unlinked GitHub project items with the jira
+// issues of the project that are not linked yet by their title, and links
+// the accepted pairs the same way the link command does.
+func AdoptAction(args Cmd) {
+	if args.Adopt == nil {
+		exitOnInvalidCall("adopt")
+	}
+	cmd := args.Adopt
+
+	if cmd.Output != nil && cmd.From != nil {
+		exitOnConflictingFlags("--output", "--from")
+	}
+	if cmd.MinScore < 0 || cmd.MinScore > 1 {
+		exitFromErr(fmt.Errorf(`"--min-score" should be between 0 and 1`))
+	}
+
+	env := newLinkEnv(args, cmd.Config, cmd.Project)
+	defer env.m.Close()
+
+	var candidates []adoptCandidate
+	var err error
+	if cmd.From != nil {
+		candidates, err = readAdoptCSV(*cmd.From)
+	} else {
+		candidates, err = findAdoptCandidates(env, deref(cmd.JQL), cmd.MinScore)
+	}
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	if cmd.Output != nil {
+		if err := writeAdoptCSV(*cmd.Output, candidates); err != nil {
+			exitFromErr(err)
+		}
+		fmt.Printf("wrote %d candidate pairs to %s\n", len(candidates), *cmd.Output)
+		return
+	}
+
+	if cmd.From == nil {
+		candidates = promptAdoptCandidates(os.Stdin, os.Stdout, candidates)
+	}
+
+	linked, failed := 0, 0
+	for _, c := range candidates {
+		if !c.Accept {
+			continue
+		}
+		if _, err := linkItem(env.ctx, env.config, env.projPos, env.jc, env.gh, *env.p, c.ItemID, c.JiraKey); err != nil {
+			env.log.WithFields(logrus.Fields{"err": err, "project": cmd.Project, "itemId": c.ItemID, "jiraKey": c.JiraKey}).Errorln("linking item failed")
+			failed++
+			continue
+		}
+		linked++
+	}
+
+	fmt.Printf("linked %d items, %d failed\n", linked, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// findAdoptCandidates pairs the unlinked GitHub project items with the jira
+// issues that are not linked to an item, best scores first. Every item and
+// jira issue is part of one pair at most.
+func findAdoptCandidates(env linkEnv, jql string, minScore float64) ([]adoptCandidate, error) {
+	remoteIssues, _, err := fetchRemoteIssues(env.ctx, env.gh, env.p.ID)
+	if err != nil {
+		return nil, err
+	}
+	remoteIssues = filterSyncableIssues(remoteIssues)
+
+	linkedKeys := []string{}
+	for _, ri := range remoteIssues {
+		if key := getJiraIssueKey(ri.JiraUrl.Text); key != "" {
+			linkedKeys = append(linkedKeys, key)
+		}
+	}
+	stored, err := env.p.GetIssuesWithUrl()
+	if err != nil {
+		return nil, err
+	}
+	for _, is := range stored {
+		linkedKeys = append(linkedKeys, getJiraIssueKey(is.JiraURL))
+	}
+
+	summaries, err := searchJiraSummaries(env.ctx, env.jc, env.config, env.projPos, jql)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := ""
+	if p := env.config.Projects[env.projPos].Jira.IssuePrefix; p != nil {
+		prefix = helpers.NormalizeText(*p)
+	}
+
+	pairs := []adoptCandidate{}
+	for _, ri := range remoteIssues {
+		if ri.JiraUrl.Text != nil && *ri.JiraUrl.Text != "" {
+			continue
+		}
+		for key, summary := range summaries {
+			if slices.Contains(linkedKeys, key) {
+				continue
+			}
+			normalized := helpers.NormalizeText(summary)
+			if prefix != "" && strings.HasPrefix(normalized, prefix+" ") {
+				normalized = normalized[len(prefix)+1:]
+			}
+			score := helpers.Similarity(ri.Title.Text, normalized)
+			if score < minScore {
+				continue
+			}
+			pairs = append(pairs, adoptCandidate{
+				ItemID:  ri.ID,
+				Title:   ri.Title.Text,
+				JiraKey: key,
+				Summary: summary,
+				Score:   score,
+			})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].Score != pairs[j].Score {
+			return pairs[i].Score > pairs[j].Score
+		}
+		return pairs[i].ItemID < pairs[j].ItemID
+	})
+
+	candidates := []adoptCandidate{}
+	usedItems := map[string]bool{}
+	usedKeys := map[string]bool{}
+	for _, pair := range pairs {
+		if usedItems[pair.ItemID] || usedKeys[pair.JiraKey] {
+			continue
+		}
+		usedItems[pair.ItemID] = true
+		usedKeys[pair.JiraKey] = true
+		pair.Accept = pair.Score >= ADOPT_ACCEPT_SCORE
+		candidates = append(candidates, pair)
+	}
+
+	return candidates, nil
+}
+
+// searchJiraSummaries retrieves the summaries by key of the jira project
+// issues matching the given extra JQL filter.
+func searchJiraSummaries(ctx context.Context, jc *jira.Client, config Config, projPos int, filter string) (map[string]string, error) {
+	jql := fmt.Sprintf(`project = "%s"`, config.Projects[projPos].Jira.ProjectKey)
+	if filter != "" {
+		jql = fmt.Sprintf("%s AND (%s)", jql, filter)
+	}
+
+	summaries := map[string]string{}
+	for startAt := 0; ; {
+		result, _, err := jc.Issue.Search.Post(ctx, jql, []string{"summary"}, nil, startAt, ADOPT_SEARCH_PAGE_SIZE, "")
+		if err != nil {
+			return nil, fmt.Errorf("searching jira issues failed: %w", err)
+		}
+		for _, issue := range result.Issues {
+			if issue.Fields != nil {
+				summaries[issue.Key] = issue.Fields.Summary
+			}
+		}
+		startAt += len(result.Issues)
+		if len(result.Issues) == 0 || startAt >= result.Total {
+			break
+		}
+	}
+
+	return summaries, nil
+}
+
+// promptAdoptCandidates asks whether to link every candidate pair, answering
+// "q" (or closing the input) rejects the remaining ones.
+func promptAdoptCandidates(in io.Reader, out io.Writer, candidates []adoptCandidate) []adoptCandidate {
+	scanner := bufio.NewScanner(in)
+	quit := false
+	for i := range candidates {
+		c := &candidates[i]
+		c.Accept = false
+		if quit {
+			continue
+		}
+
+		fmt.Fprintf(out, "[%d/%d] score %.2f\n  item %s: %s\n  jira %s: %s\nlink? [y/N/q] ", i+1, len(candidates), c.Score, c.ItemID, c.Title, c.JiraKey, c.Summary)
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			quit = true
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+		case "y", "yes":
+			c.Accept = true
+		case "q":
+			quit = true
+		}
+	}
+	return candidates
+}
+
+// writeAdoptCSV writes the candidate pairs with the ADOPT_CSV_HEADER columns.
+func writeAdoptCSV(path string, candidates []adoptCandidate) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write(ADOPT_CSV_HEADER)
+	for _, c := range candidates {
+		w.Write([]string{
+			c.ItemID,
+			c.Title,
+			c.JiraKey,
+			c.Summary,
+			strconv.FormatFloat(c.Score, 'f', 2, 64),
+			strconv.FormatBool(c.Accept),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// readAdoptCSV reads the candidate pairs written by writeAdoptCSV, the
+// "accept" column takes true, yes or y (case insensitive) as accepted.
+func readAdoptCSV(path string) ([]adoptCandidate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []adoptCandidate{}, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	for _, name := range []string{"itemId", "jiraKey", "accept"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf(`csv column "%s" is missing`, name)
+		}
+	}
+
+	candidates := []adoptCandidate{}
+	for line, record := range records[1:] {
+		c := adoptCandidate{
+			ItemID:  strings.TrimSpace(record[columns["itemId"]]),
+			JiraKey: strings.TrimSpace(record[columns["jiraKey"]]),
+		}
+		switch strings.ToLower(strings.TrimSpace(record[columns["accept"]])) {
+		case "true", "yes", "y":
+			c.Accept = true
+		}
+		if c.Accept && (c.ItemID == "" || c.JiraKey == "") {
+			return nil, fmt.Errorf(`line %d is accepted but lacks of an "itemId" or a "jiraKey"`, line+2)
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, nil
+}
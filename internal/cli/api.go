@@ -0,0 +1,482 @@
+package cli
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/metrics"
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DEFAULT_API_ADDRESS       = ":8080"
+	API_SHUTDOWN_GRACE_PERIOD = 5 * time.Second
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// apiServer serves the management api, it shares the projects status with
+// the sync loops so syncs can be requested through it.
+type apiServer struct {
+	args  Cmd
+	state *syncState
+	m     *models.Models
+	ghs   *githubClients
+	log   *logrus.Logger
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type apiProject struct {
+	Name            string     `json:"name"`
+	GitHubProjectID string     `json:"githubProjectId"`
+	JiraProjectKey  string     `json:"jiraProjectKey"`
+	Running         bool       `json:"running"`
+	Failures        int        `json:"failures"`
+	GaveUp          bool       `json:"gaveUp"`
+	LastError       *string    `json:"lastError"`
+	LastSuccessAt   *time.Time `json:"lastSuccessAt"`
+}
+
+type apiIssue struct {
+	GitHubProjectID string   `json:"githubProjectId"`
+	GitHubItemID    string   `json:"githubItemId"`
+	Title           string   `json:"title"`
+	Status          *string  `json:"status"`
+	JiraURL         *string  `json:"jiraUrl"`
+	JiraKey         *string  `json:"jiraKey"`
+	JiraIssueType   *string  `json:"jiraIssueType"`
+	Estimate        *int     `json:"estimate"`
+	Assignees       []string `json:"assignees"`
+	Repository      *string  `json:"repository"`
+}
+
+type apiLinkRequest struct {
+	JiraKey string `json:"jiraKey"`
+	Force   bool   `json:"force"`
+}
+
+type apiTransitionRequest struct {
+	Status string `json:"status"`
+}
+
+func newApiIssue(is models.Issue) apiIssue {
+	issue := apiIssue{
+		GitHubProjectID: is.GitHubProjectID,
+		GitHubItemID:    is.GitHubID,
+		Title:           is.Title,
+		Status:          (*string)(is.Status),
+		JiraURL:         is.JiraURL,
+		JiraIssueType:   is.JiraIssueType,
+		Estimate:        is.Estimate,
+		Assignees:       is.Assignees,
+		Repository:      is.Repository,
+	}
+	if key := getJiraIssueKey(is.JiraURL); key != "" {
+		issue.JiraKey = &key
+	}
+	return issue
+}
+
+// startAPIServer serves the management api until ctx is cancelled. The
+// server is not started without an api token, as every endpoint but the
+// openapi description requires it.
+func startAPIServer(ctx context.Context, args Cmd, state *syncState, m *models.Models, ghs *githubClients, log *logrus.Logger) error {
+	if args.APIToken == nil || *args.APIToken == "" {
+		return errors.New(`please set the "API_TOKEN" env variable when "enableApi" is set`)
+	}
+
+	config, _ := state.get()
+	address := DEFAULT_API_ADDRESS
+	if config.APIAddress != nil {
+		address = *config.APIAddress
+	}
+
+	api := &apiServer{args: args, state: state, m: m, ghs: ghs, log: log}
+	server := &http.Server{Addr: address, Handler: api.routes(*args.APIToken)}
+
+	go func() {
+		log.WithFields(logrus.Fields{"address": address}).Infoln("serving management api")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithFields(logrus.Fields{"err": err, "address": address}).Errorln("serving management api failed")
+		}
+	}()
+
+	context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), API_SHUTDOWN_GRACE_PERIOD)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	})
+
+	return nil
+}
+
+func (api *apiServer) routes(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.yaml", api.getOpenAPISpec)
+
+	protected := http.NewServeMux()
+	protected.HandleFunc("GET /projects", api.listProjects)
+	protected.HandleFunc("POST /projects/{name}/sync", api.syncProject)
+	protected.HandleFunc("GET /projects/{name}/issues", api.listIssues)
+	protected.HandleFunc("GET /projects/{name}/issues/{itemId}", api.getIssue)
+	protected.HandleFunc("POST /projects/{name}/issues/{itemId}/sync", api.syncIssue)
+	protected.HandleFunc("PUT /projects/{name}/issues/{itemId}/link", api.linkIssue)
+	protected.HandleFunc("DELETE /projects/{name}/issues/{itemId}/link", api.unlinkIssue)
+	protected.HandleFunc("POST /projects/{name}/issues/{itemId}/transition", api.transitionIssue)
+	protected.HandleFunc("GET /audit", api.listAudit)
+	mux.Handle("/", withBearerToken(token, protected))
+
+	return mux
+}
+
+// withBearerToken rejects the requests that don't carry the given token in
+// the "Authorization: Bearer <token>" header.
+func withBearerToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, apiError{Error: "invalid or missing api token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, apiError{Error: err.Error()})
+}
+
+// getProjectPos returns the position of the project named after the "name"
+// path value, writing a not found response if there is no such project.
+func (api *apiServer) getProjectPos(w http.ResponseWriter, r *http.Request, config Config) (int, bool) {
+	name := r.PathValue("name")
+	if projPos := getProjectPosByName(config, name); projPos != -1 {
+		return projPos, true
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf(`project "%s" not found`, name))
+	return 0, false
+}
+
+// getProject returns the stored project, writing a conflict response if the
+// project was not synced yet.
+func (api *apiServer) getProject(w http.ResponseWriter, config Config, projPos int) (*models.Project, bool) {
+	projectCfg := config.Projects[projPos]
+	p, err := api.m.Projects.Get(projectCfg.Github.ProjectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if p == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf(`project "%s" was not synced yet`, projectCfg.Name))
+		return nil, false
+	}
+	return p, true
+}
+
+func (api *apiServer) getOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+func (api *apiServer) listProjects(w http.ResponseWriter, r *http.Request) {
+	config, statuses := api.state.get()
+	projects := []apiProject{}
+	for i, proj := range config.Projects {
+		status := statuses[i].snapshot()
+		project := apiProject{
+			Name:            proj.Name,
+			GitHubProjectID: proj.Github.ProjectID,
+			JiraProjectKey:  proj.Jira.ProjectKey,
+			Running:         status.Running,
+			Failures:        status.Failures,
+			GaveUp:          status.GaveUp,
+		}
+		if status.LastErr != nil {
+			lastErr := status.LastErr.Error()
+			project.LastError = &lastErr
+		}
+		if !status.LastSuccessAt.IsZero() {
+			project.LastSuccessAt = &status.LastSuccessAt
+		}
+		projects = append(projects, project)
+	}
+
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (api *apiServer) syncProject(w http.ResponseWriter, r *http.Request) {
+	config, statuses := api.state.get()
+	projPos, ok := api.getProjectPos(w, r, config)
+	if !ok {
+		return
+	}
+	api.requestSync(w, statuses[projPos], "")
+}
+
+func (api *apiServer) syncIssue(w http.ResponseWriter, r *http.Request) {
+	config, statuses := api.state.get()
+	projPos, ok := api.getProjectPos(w, r, config)
+	if !ok {
+		return
+	}
+	api.requestSync(w, statuses[projPos], r.PathValue("itemId"))
+}
+
+func (api *apiServer) requestSync(w http.ResponseWriter, status *projectStatus, itemId string) {
+	switch err := status.requestSync(itemId); {
+	case errors.Is(err, errProjectNotRunning):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, errTooManySyncRequests):
+		writeError(w, http.StatusTooManyRequests, err)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (api *apiServer) listIssues(w http.ResponseWriter, r *http.Request) {
+	config, _ := api.state.get()
+	projPos, ok := api.getProjectPos(w, r, config)
+	if !ok {
+		return
+	}
+	p, ok := api.getProject(w, config, projPos)
+	if !ok {
+		return
+	}
+
+	issues, err := p.GetAllIssues()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	result := []apiIssue{}
+	for _, is := range issues {
+		result = append(result, newApiIssue(*is))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (api *apiServer) getIssue(w http.ResponseWriter, r *http.Request) {
+	config, _ := api.state.get()
+	projPos, ok := api.getProjectPos(w, r, config)
+	if !ok {
+		return
+	}
+	p, ok := api.getProject(w, config, projPos)
+	if !ok {
+		return
+	}
+
+	is, err := p.GetIssue(r.PathValue("itemId"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if is == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`issue "%s" not found`, r.PathValue("itemId")))
+		return
+	}
+	writeJSON(w, http.StatusOK, newApiIssue(*is))
+}
+
+func (api *apiServer) linkIssue(w http.ResponseWriter, r *http.Request) {
+	config, _ := api.state.get()
+	projPos, ok := api.getProjectPos(w, r, config)
+	if !ok {
+		return
+	}
+	p, ok := api.getProject(w, config, projPos)
+	if !ok {
+		return
+	}
+
+	var body apiLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.JiraKey == "" {
+		writeError(w, http.StatusBadRequest, errors.New(`request body should be a json object with a "jiraKey" property`))
+		return
+	}
+
+	jc, err := newProjectJiraClient(api.args, config, projPos)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	gh, err := api.ghs.get(config.Projects[projPos].Name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	ctx := metrics.WithProject(r.Context(), config.Projects[projPos].Name)
+
+	if !body.Force {
+		if err := checkLinkable(*p, config, projPos, r.PathValue("itemId"), body.JiraKey); err != nil {
+			writeError(w, http.StatusConflict, fmt.Errorf(`%w, set "force" to link it anyway`, err))
+			return
+		}
+	}
+
+	is, err := linkItem(ctx, config, projPos, jc, gh, *p, r.PathValue("itemId"), body.JiraKey)
+	if err != nil {
+		api.log.WithFields(logrus.Fields{"err": err, "project": config.Projects[projPos].Name, "itemId": r.PathValue("itemId"), "jiraKey": body.JiraKey}).Errorln("linking item failed")
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newApiIssue(*is))
+}
+
+func (api *apiServer) unlinkIssue(w http.ResponseWriter, r *http.Request) {
+	config, _ := api.state.get()
+	projPos, ok := api.getProjectPos(w, r, config)
+	if !ok {
+		return
+	}
+	p, ok := api.getProject(w, config, projPos)
+	if !ok {
+		return
+	}
+
+	jc, err := newProjectJiraClient(api.args, config, projPos)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	gh, err := api.ghs.get(config.Projects[projPos].Name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	ctx := metrics.WithProject(r.Context(), config.Projects[projPos].Name)
+
+	if err := unlinkItem(ctx, jc, gh, *p, r.PathValue("itemId")); err != nil {
+		api.log.WithFields(logrus.Fields{"err": err, "project": config.Projects[projPos].Name, "itemId": r.PathValue("itemId")}).Errorln("unlinking item failed")
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// transitionIssue runs the configured transitions of the issue type towards
+// the given status against the jira issue and stores the issue with it, the
+// GitHub item is left untouched.
+func (api *apiServer) transitionIssue(w http.ResponseWriter, r *http.Request) {
+	config, _ := api.state.get()
+	projPos, ok := api.getProjectPos(w, r, config)
+	if !ok {
+		return
+	}
+	p, ok := api.getProject(w, config, projPos)
+	if !ok {
+		return
+	}
+
+	var body apiTransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New(`request body should be a json object with a "status" property`))
+		return
+	}
+	status := models.IssueStatus(body.Status)
+	if status != models.STATUS_WIP && status != models.STATUS_DONE {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(`"status" property should be one of [%s, %s]`, models.STATUS_WIP, models.STATUS_DONE))
+		return
+	}
+
+	is, err := p.GetIssue(r.PathValue("itemId"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if is == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`issue "%s" not found`, r.PathValue("itemId")))
+		return
+	}
+	key := getJiraIssueKey(is.JiraURL)
+	if key == "" {
+		writeError(w, http.StatusConflict, fmt.Errorf(`issue "%s" is not linked to a jira issue`, is.GitHubID))
+		return
+	}
+
+	jc, err := newProjectJiraClient(api.args, config, projPos)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	ctx := metrics.WithProject(r.Context(), config.Projects[projPos].Name)
+
+	// an issue already in progress only needs the transitions to done
+	err = nil
+	if status == models.STATUS_WIP || is.Status == nil || *is.Status == models.STATUS_TODO {
+		err = transitionToWip(ctx, jc, *p, nil, key, projPos, config, *is)
+	}
+	if err == nil && status == models.STATUS_DONE {
+		err = transitionToDone(ctx, jc, *p, nil, key, projPos, config, *is)
+	}
+	if err != nil {
+		api.log.WithFields(logrus.Fields{"err": err, "project": config.Projects[projPos].Name, "itemId": is.GitHubID, "jiraKey": key}).Errorln("transitioning jira issue failed")
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	if _, err := p.UpsertIssue(is.GitHubID, is.Title, &status, is.JiraURL, is.JiraIssueType, is.Repository, is.Estimate, &is.Assignees); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listAudit lists the audit log entries that match the query filters.
+func (api *apiServer) listAudit(w http.ResponseWriter, r *http.Request) {
+	config, _ := api.state.get()
+	query := r.URL.Query()
+
+	limit := models.DEFAULT_AUDIT_LIMIT
+	if v := query.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New(`"limit" should be a positive number`))
+			return
+		}
+	}
+
+	filter, err := newAuditFilter(
+		config,
+		query.Get("project"),
+		query.Get("itemId"),
+		query.Get("jiraKey"),
+		query.Get("action"),
+		query.Get("result"),
+		query.Get("since"),
+		limit,
+	)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	entries, err := api.m.Audits.List(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newAuditEntries(config, entries))
+}
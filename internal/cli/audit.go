@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	AUDIT_FORMAT_TEXT = "text"
+	AUDIT_FORMAT_JSON = "json"
+)
+
+type AuditCmd struct {
+	List *AuditListCmd `arg:"subcommand:list" help:"list the actions taken against Jira and GitHub, newest first"`
+}
+
+type AuditListCmd struct {
+	Config  string  `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project *string `arg:"--project" help:"only list the actions of the given sync project" placeholder:"<NAME>"`
+	Item    *string `arg:"--item" help:"only list the actions of the given GitHub project item" placeholder:"<ID>"`
+	JiraKey *string `arg:"--jira-key" help:"only list the actions of the given Jira issue" placeholder:"<KEY>"`
+	Action  *string `arg:"--action" help:"only list the given action [jira_create, jira_transition, jira_field_update, jira_comment, jira_property_set, jira_property_delete, github_field_write, github_field_clear]" placeholder:"<ACTION>"`
+	Result  *string `arg:"--result" help:"only list the actions with the given result [success, failure]" placeholder:"<RESULT>"`
+	Since   *string `arg:"--since" help:"only list the actions since a duration ago (i.e. 24h) or a RFC3339 time" placeholder:"<SINCE>"`
+	Limit   int     `arg:"--limit" default:"100" help:"maximum number of actions listed"`
+	Format  string  `arg:"--format" default:"text" help:"output format [text, json]" placeholder:"<FORMAT>"`
+}
+
+// AuditEntry is the json representation of an audit log entry.
+type AuditEntry struct {
+	ID              int64     `json:"id"`
+	CreatedAt       time.Time `json:"createdAt"`
+	Project         *string   `json:"project"`
+	GitHubProjectID string    `json:"githubProjectId"`
+	GitHubItemID    string    `json:"githubItemId"`
+	JiraKey         *string   `json:"jiraKey"`
+	Action          string    `json:"action"`
+	Summary         string    `json:"summary"`
+	Result          string    `json:"result"`
+	Error           *string   `json:"error"`
+}
+
+// recordAudit records an outbound mutation in the audit log. Failing to
+// record it is reported but doesn't fail the mutation.
+func recordAudit(p models.Project, itemId, key string, action models.AuditAction, summary string, err error) {
+	if _, auditErr := p.RecordAudit(itemId, key, action, summary, err); auditErr != nil {
+		fmt.Fprintf(os.Stderr, "error: recording audit entry failed: %s\n", auditErr.Error())
+	}
+}
+
+// AuditListAction prints the audit log entries that match the given filters.
+func AuditListAction(args Cmd) {
+	if args.Audit == nil || args.Audit.List == nil {
+		exitOnInvalidCall("audit list")
+	}
+	cmd := args.Audit.List
+
+	if cmd.Format != AUDIT_FORMAT_TEXT && cmd.Format != AUDIT_FORMAT_JSON {
+		err := fmt.Errorf(`"--format" should be one of [%s, %s]`, AUDIT_FORMAT_TEXT, AUDIT_FORMAT_JSON)
+		exitFromErr(err)
+	}
+
+	log := newLogger(logrus.InfoLevel)
+	config, err := readConfig(cmd.Config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	filter, err := newAuditFilter(config, deref(cmd.Project), deref(cmd.Item), deref(cmd.JiraKey), deref(cmd.Action), deref(cmd.Result), deref(cmd.Since), cmd.Limit)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	m, err := initializeModels(args, cmd.Config, config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	defer m.Close()
+
+	entries, err := m.Audits.List(filter)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	if err := writeAuditEntries(os.Stdout, config, entries, cmd.Format); err != nil {
+		exitFromErr(err)
+	}
+}
+
+// newAuditFilter validates the audit filters given by the user, project is
+// the sync project name.
+func newAuditFilter(config Config, project, itemId, jiraKey, action, result, since string, limit int) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		GitHubID: itemId,
+		JiraKey:  jiraKey,
+		Action:   models.AuditAction(action),
+		Result:   models.AuditResult(result),
+		Limit:    limit,
+	}
+
+	if project != "" {
+		projPos := getProjectPosByName(config, project)
+		if projPos == -1 {
+			return filter, fmt.Errorf(`sync project "%s" not found in config`, project)
+		}
+		filter.GitHubProjectID = config.Projects[projPos].Github.ProjectID
+	}
+
+	switch filter.Action {
+	case "",
+		models.AUDIT_ACTION_JIRA_CREATE,
+		models.AUDIT_ACTION_JIRA_TRANSITION,
+		models.AUDIT_ACTION_JIRA_FIELD_UPDATE,
+		models.AUDIT_ACTION_JIRA_COMMENT,
+		models.AUDIT_ACTION_JIRA_PROPERTY_SET,
+		models.AUDIT_ACTION_JIRA_PROPERTY_DELETE,
+		models.AUDIT_ACTION_GITHUB_FIELD_WRITE,
+		models.AUDIT_ACTION_GITHUB_FIELD_CLEAR:
+	default:
+		return filter, fmt.Errorf(`unknown audit action "%s"`, action)
+	}
+
+	switch filter.Result {
+	case "", models.AUDIT_RESULT_SUCCESS, models.AUDIT_RESULT_FAILURE:
+	default:
+		return filter, fmt.Errorf(`audit result should be one of [%s, %s]`, models.AUDIT_RESULT_SUCCESS, models.AUDIT_RESULT_FAILURE)
+	}
+
+	if since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			filter.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = t
+		} else {
+			return filter, fmt.Errorf(`since "%s" is neither a duration nor a RFC3339 time`, since)
+		}
+	}
+
+	return filter, nil
+}
+
+func newAuditEntries(config Config, entries []*models.AuditEntry) []AuditEntry {
+	result := []AuditEntry{}
+	for _, entry := range entries {
+		e := AuditEntry{
+			ID:              entry.ID,
+			CreatedAt:       entry.CreatedAt,
+			GitHubProjectID: entry.GitHubProjectID,
+			GitHubItemID:    entry.GitHubID,
+			JiraKey:         entry.JiraKey,
+			Action:          string(entry.Action),
+			Summary:         entry.Summary,
+			Result:          string(entry.Result),
+			Error:           entry.Error,
+		}
+		if projPos := getProjectPosById(config, entry.GitHubProjectID); projPos != -1 {
+			e.Project = &config.Projects[projPos].Name
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+// writeAuditEntries writes the audit entries in the given format.
+func writeAuditEntries(w io.Writer, config Config, entries []*models.AuditEntry, format string) error {
+	result := newAuditEntries(config, entries)
+
+	if format == AUDIT_FORMAT_JSON {
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTIME\tPROJECT\tITEM\tJIRA KEY\tACTION\tRESULT\tSUMMARY\tERROR")
+	for _, e := range result {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt.Local().Format(time.DateTime),
+			derefOr(e.Project, e.GitHubProjectID),
+			e.GitHubItemID,
+			derefOr(e.JiraKey, "-"),
+			e.Action,
+			e.Result,
+			e.Summary,
+			derefOr(e.Error, "-"),
+		)
+	}
+	return tw.Flush()
+}
+
+// getProjectPosByName returns the position of the sync project with the
+// given name, or -1 if there's none.
+func getProjectPosByName(config Config, name string) int {
+	for i, proj := range config.Projects {
+		if proj.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// getProjectPosById returns the position of the sync project with the
+// given GitHub project id, or -1 if there's none.
+func getProjectPosById(config Config, projectId string) int {
+	for i, proj := range config.Projects {
+		if proj.Github.ProjectID == projectId {
+			return i
+		}
+	}
+	return -1
+}
+
+func deref(s *string) string {
+	return derefOr(s, "")
+}
+
+func derefOr(s *string, fallback string) string {
+	if s == nil {
+		return fallback
+	}
+	return *s
+}
@@ -15,13 +15,25 @@ const (
 )
 
 type Cmd struct {
-	Version     *bool      `arg:"--version" help:"display the program version"`
-	GithubToken *string    `arg:"env:GITHUB_TOKEN,--gh-token" help:"GitHub token" placeholder:"<STRING>"`
-	JiraEmail   *string    `arg:"env:JIRA_EMAIL,--jira-email" help:"Jira email used for basic auth" placeholder:"<STRING>"`
-	Debug       *bool      `arg:"--debug" help:"enables debug mode"`
-	JiraToken   *string    `arg:"env:JIRA_TOKEN,--jira-token" help:"Jira api token used for basic auth" placeholder:"<STRING>"`
-	Github      *GithubCmd `arg:"subcommand:github" help:"GitHub utilities" `
-	Sync        *SyncCmd   `arg:"subcommand:sync" help:"sync GitHub project tickets with Jira"`
+	Version     *bool           `arg:"--version" help:"display the program version"`
+	GithubToken *string         `arg:"env:GITHUB_TOKEN,--gh-token" help:"GitHub token" placeholder:"<STRING>"`
+	JiraEmail   *string         `arg:"env:JIRA_EMAIL,--jira-email" help:"Jira email used for basic auth" placeholder:"<STRING>"`
+	Debug       *bool           `arg:"--debug" help:"enables debug mode"`
+	JiraToken   *string         `arg:"env:JIRA_TOKEN,--jira-token" help:"Jira api token used for basic auth" placeholder:"<STRING>"`
+	DB          *string         `arg:"env:DB_PATH,--db" help:"path to the local storage, overrides the database.path option" placeholder:"<PATH>"`
+	APIToken    *string         `arg:"env:API_TOKEN,--api-token" help:"token required by the management api (enableApi)" placeholder:"<STRING>"`
+	Github      *GithubCmd      `arg:"subcommand:github" help:"GitHub utilities" `
+	Sync        *SyncCmd        `arg:"subcommand:sync" help:"sync GitHub project tickets with Jira"`
+	State       *StateCmd       `arg:"subcommand:state" help:"local sync state utilities"`
+	Audit       *AuditCmd       `arg:"subcommand:audit" help:"audit log of the actions taken against Jira and GitHub"`
+	Healthcheck *HealthcheckCmd `arg:"subcommand:healthcheck" help:"check the health of a running sync (for docker HEALTHCHECK)"`
+	Link        *LinkCmd        `arg:"subcommand:link" help:"link a GitHub project item to an existing Jira issue"`
+	Unlink      *UnlinkCmd      `arg:"subcommand:unlink" help:"remove the link between a GitHub project item and its Jira issue"`
+	Adopt       *AdoptCmd       `arg:"subcommand:adopt" help:"link the GitHub project items to the existing Jira issues with a similar summary"`
+	Db          *DbCmd          `arg:"subcommand:db" help:"local storage utilities"`
+	Report      *ReportCmd      `arg:"subcommand:report" help:"reports built from the synced items history"`
+	Config      *ConfigCmd      `arg:"subcommand:config" help:"config file utilities"`
+	Doctor      *DoctorCmd      `arg:"subcommand:doctor" help:"diagnose the credentials, permissions and secrets of the sync projects"`
 }
 
 func newLogger(level logrus.Level) *logrus.Logger {
@@ -40,6 +52,11 @@ func PrintVersion() {
 // DetectAndRunAction chooses the proper action to be executed
 // based in the given args.
 func DetectAndRunAction(args Cmd, parser *arg.Parser) {
+	// doctor reports the secrets that can't be read along with its checks
+	if err := readSecretFiles(&args); err != nil && args.Doctor == nil {
+		exitFromErr(err)
+	}
+
 	if args.Version != nil && *args.Version == true {
 		fmt.Println(VERSION)
 		os.Exit(0)
@@ -56,6 +73,66 @@ func DetectAndRunAction(args Cmd, parser *arg.Parser) {
 		}
 	case args.Sync != nil:
 		SyncCmdAction(args)
+	case args.State != nil:
+		switch {
+		case args.State.Rebuild != nil:
+			StateRebuildAction(args)
+		case args.State.List != nil:
+			StateListAction(args)
+		case args.State.Show != nil:
+			StateShowAction(args)
+		case args.State.Export != nil:
+			StateExportAction(args)
+		case args.State.Import != nil:
+			StateImportAction(args)
+		default:
+			parser.WriteHelp(os.Stderr)
+			os.Exit(1)
+		}
+	case args.Audit != nil:
+		switch {
+		case args.Audit.List != nil:
+			AuditListAction(args)
+		default:
+			parser.WriteHelp(os.Stderr)
+			os.Exit(1)
+		}
+	case args.Healthcheck != nil:
+		HealthcheckAction(args)
+	case args.Link != nil:
+		LinkAction(args)
+	case args.Unlink != nil:
+		UnlinkAction(args)
+	case args.Adopt != nil:
+		AdoptAction(args)
+	case args.Db != nil:
+		switch {
+		case args.Db.Migrate != nil:
+			DbMigrateAction(args)
+		default:
+			parser.WriteHelp(os.Stderr)
+			os.Exit(1)
+		}
+	case args.Report != nil:
+		switch {
+		case args.Report.CycleTime != nil:
+			ReportCycleTimeAction(args)
+		default:
+			parser.WriteHelp(os.Stderr)
+			os.Exit(1)
+		}
+	case args.Config != nil:
+		switch {
+		case args.Config.Validate != nil:
+			ConfigValidateAction(args)
+		case args.Config.Init != nil:
+			ConfigInitAction(args)
+		default:
+			parser.WriteHelp(os.Stderr)
+			os.Exit(1)
+		}
+	case args.Doctor != nil:
+		DoctorAction(args)
 	default:
 		parser.WriteHelp(os.Stderr)
 		os.Exit(1)
@@ -0,0 +1,309 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	jira "github.com/ctreminiom/go-atlassian/jira/v3"
+	jiramodels "github.com/ctreminiom/go-atlassian/pkg/infra/models"
+	"github.com/sirupsen/logrus"
+)
+
+// results of an online config check
+const (
+	CHECK_OK   = "ok"
+	CHECK_WARN = "warn"
+	CHECK_FAIL = "fail"
+)
+
+type ConfigCmd struct {
+	Validate *ConfigValidateCmd `arg:"subcommand:validate" help:"validate a config file"`
+	Init     *ConfigInitCmd     `arg:"subcommand:init" help:"write a config file by answering questions about the GitHub and Jira projects"`
+}
+
+type ConfigValidateCmd struct {
+	Config  string  `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Online  bool    `arg:"--online" help:"also check the config against GitHub and Jira"`
+	Project *string `arg:"--project" help:"only check the given sync project online" placeholder:"<NAME>"`
+}
+
+// configCheck is the result of checking a piece of the config against
+// GitHub or Jira.
+type configCheck struct {
+	Status  string
+	Message string
+	Hint    string // how to fix a failed or warned check
+}
+
+// ConfigValidateAction validates the config file and, with --online, checks
+// every sync project against GitHub and Jira, exiting with 1 if any check
+// fails.
+func ConfigValidateAction(args Cmd) {
+	if args.Config == nil || args.Config.Validate == nil {
+		exitOnInvalidCall("config validate")
+	}
+	cmd := args.Config.Validate
+
+	level := logrus.InfoLevel
+	if args.Debug != nil && *args.Debug == true {
+		level = logrus.DebugLevel
+	}
+	log := newLogger(level)
+
+	config, err := readConfig(cmd.Config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	if !cmd.Online {
+		fmt.Printf("config %s is valid\n", cmd.Config)
+		return
+	}
+
+	projects := []int{}
+	for i := range config.Projects {
+		if cmd.Project == nil || config.Projects[i].Name == *cmd.Project {
+			projects = append(projects, i)
+		}
+	}
+	if cmd.Project != nil && len(projects) == 0 {
+		exitFromErr(fmt.Errorf(`sync project "%s" not found in config`, *cmd.Project))
+	}
+
+	// the checks already report what went wrong, the errors logged along the
+	// way are only shown in debug mode
+	if level != logrus.DebugLevel {
+		log.SetOutput(io.Discard)
+	}
+
+	failed := 0
+	for _, projPos := range projects {
+		fmt.Printf("project \"%s\":\n", config.Projects[projPos].Name)
+		failed += printChecks(checkProjectOnline(context.Background(), args, config, projPos, log))
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d checks failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Printf("config %s is valid\n", cmd.Config)
+}
+
+// checkProjectOnline checks a sync project config against GitHub and Jira.
+// Jira checks are skipped once the credentials or the project fail.
+func checkProjectOnline(ctx context.Context, args Cmd, config Config, projPos int, log *logrus.Logger) []configCheck {
+	checks := []configCheck{checkGithubProject(ctx, args, config, projPos, log)}
+
+	jc, err := newProjectJiraClient(args, config, projPos)
+	if err != nil {
+		return append(checks, failCheck("jira client: %s", err))
+	}
+	me, _, err := jc.MySelf.Details(ctx, nil)
+	if err != nil {
+		return append(checks, failCheck("jira credentials rejected: %s", err))
+	}
+	checks = append(checks, okCheck("jira credentials work, authenticated as %s", me.EmailAddress))
+
+	projectCfg := config.Projects[projPos]
+	project, _, err := jc.Project.Get(ctx, projectCfg.Jira.ProjectKey, []string{"issueTypes"})
+	if err != nil {
+		return append(checks, failCheck(`jira project "%s" not found: %s`, projectCfg.Jira.ProjectKey, err))
+	}
+	checks = append(checks, okCheck(`jira project "%s" exists`, project.Key))
+
+	issueTypes := map[string]*jiramodels.IssueTypeScheme{}
+	for _, issueCfg := range projectCfg.Jira.Issues {
+		idx := slices.IndexFunc(project.IssueTypes, func(it *jiramodels.IssueTypeScheme) bool {
+			return strings.EqualFold(it.Name, issueCfg.Type)
+		})
+		if idx == -1 {
+			checks = append(checks, failCheck(`jira issue type "%s" not found in project "%s"`, issueCfg.Type, project.Key))
+			continue
+		}
+		issueTypes[issueCfg.Type] = project.IssueTypes[idx]
+		checks = append(checks, okCheck(`jira issue type "%s" exists`, issueCfg.Type))
+	}
+
+	checks = append(checks, checkTransitions(ctx, jc, config, projPos, project, issueTypes)...)
+	checks = append(checks, checkEstimateField(ctx, jc, config, projPos, issueTypes)...)
+	checks = append(checks, checkAssignees(ctx, jc, config, projPos)...)
+
+	return checks
+}
+
+// checkGithubProject checks that the GitHub project exists and has the
+// required fields.
+func checkGithubProject(ctx context.Context, args Cmd, config Config, projPos int, log *logrus.Logger) configCheck {
+	gh, err := newProjectGithubClient(args, config.Projects[projPos].Name)
+	if err != nil {
+		return failCheck("github client: %s", err)
+	}
+
+	if _, err := getProjectFieldsIds(ctx, config, projPos, gh, log); err != nil {
+		return failCheck("github project %s: %s", config.Projects[projPos].Github.ProjectID, strings.TrimPrefix(err.Error(), "error: "))
+	}
+	return okCheck("github project %s exists and has the required fields", config.Projects[projPos].Github.ProjectID)
+}
+
+// checkTransitions checks that the configured transitions exist in the
+// workflow of their issue type. Reading workflows requires the jira
+// administer permission, without it the check is skipped with a warning.
+func checkTransitions(ctx context.Context, jc *jira.Client, config Config, projPos int, project *jiramodels.ProjectScheme, issueTypes map[string]*jiramodels.IssueTypeScheme) []configCheck {
+	projectCfg := config.Projects[projPos]
+	onRemoved := []int{}
+	if projectCfg.Jira.OnRemoved.Action == REMOVED_ACTION_TRANSITION {
+		onRemoved = projectCfg.Jira.OnRemoved.Transitions
+	}
+
+	hasTransitions := len(onRemoved) > 0
+	for _, issueCfg := range projectCfg.Jira.Issues {
+		hasTransitions = hasTransitions || len(issueCfg.TransitionsToWIP) > 0 || len(issueCfg.TransitionsToDone) > 0
+	}
+	if !hasTransitions {
+		return nil
+	}
+
+	scheme, err := getProjectWorkflowScheme(ctx, jc, project.ID)
+	if err != nil {
+		return []configCheck{warnCheck("transitions not checked, couldn't read the project workflow scheme (requires the jira administer permission)")}
+	}
+
+	checks := []configCheck{}
+	for _, issueCfg := range projectCfg.Jira.Issues {
+		it, ok := issueTypes[issueCfg.Type]
+		if !ok {
+			continue
+		}
+
+		workflow := getIssueTypeWorkflow(ctx, jc, scheme, it.ID)
+		transitions, err := getWorkflowTransitions(ctx, jc, workflow)
+		if err != nil {
+			checks = append(checks, warnCheck(`transitions of "%s" not checked, couldn't read workflow "%s": %s`, issueCfg.Type, workflow, err))
+			continue
+		}
+
+		sets := []struct {
+			name string
+			ids  []int
+		}{
+			{"transitionsToWip", issueCfg.TransitionsToWIP},
+			{"transitionsToDone", issueCfg.TransitionsToDone},
+			{"onRemoved.transitions", onRemoved},
+		}
+		for _, set := range sets {
+			for _, id := range set.ids {
+				if !slices.ContainsFunc(transitions, func(t *jiramodels.WorkflowTransitionScheme) bool { return t.ID == strconv.Itoa(id) }) {
+					checks = append(checks, failCheck(`transition %d of "%s" %s not found in workflow "%s"`, id, issueCfg.Type, set.name, workflow))
+					continue
+				}
+				checks = append(checks, okCheck(`transition %d of "%s" %s exists`, id, issueCfg.Type, set.name))
+			}
+		}
+	}
+
+	return checks
+}
+
+// checkEstimateField checks that the estimate field is on the create screen
+// of the configured issue types, or of every issue type if none is
+// configured.
+func checkEstimateField(ctx context.Context, jc *jira.Client, config Config, projPos int, issueTypes map[string]*jiramodels.IssueTypeScheme) []configCheck {
+	projectCfg := config.Projects[projPos]
+	if projectCfg.Jira.EstimateField == nil {
+		return nil
+	}
+	field := *projectCfg.Jira.EstimateField
+
+	names := []string{}
+	for _, it := range issueTypes {
+		names = append(names, it.Name)
+	}
+	if len(projectCfg.Jira.Issues) > 0 && len(names) == 0 {
+		return nil
+	}
+
+	opts := &jiramodels.IssueMetadataCreateOptions{
+		ProjectKeys:    []string{projectCfg.Jira.ProjectKey},
+		IssueTypeNames: names,
+		Expand:         "projects.issuetypes.fields",
+	}
+	meta, _, err := jc.Issue.Metadata.Create(ctx, opts)
+	if err != nil {
+		return []configCheck{failCheck(`couldn't read the create screen fields: %s`, err)}
+	}
+
+	checks := []configCheck{}
+	for _, it := range meta.Get("projects.0.issuetypes").Array() {
+		name := it.Get("name").String()
+		if len(names) == 0 && it.Get("subtask").Bool() {
+			continue
+		}
+		if !it.Get("fields").Get(field).Exists() {
+			checks = append(checks, failCheck(`estimate field "%s" is not on the "%s" create screen`, field, name))
+			continue
+		}
+		checks = append(checks, okCheck(`estimate field "%s" is on the "%s" create screen`, field, name))
+	}
+
+	return checks
+}
+
+// checkAssignees checks that every assignee email resolves to exactly one
+// jira account.
+func checkAssignees(ctx context.Context, jc *jira.Client, config Config, projPos int) []configCheck {
+	checks := []configCheck{}
+	for _, assignee := range config.Projects[projPos].Assignees {
+		users, _, err := jc.User.Search.Do(ctx, "", assignee.JiraEmail, 0, 2)
+		if err != nil {
+			err = explainJiraNotFound(ctx, jc, err)
+		}
+		switch {
+		case err != nil:
+			checks = append(checks, failCheck(`assignee "%s" lookup failed: %s`, assignee.JiraEmail, err))
+		case len(users) == 0:
+			checks = append(checks, failCheck(`assignee "%s" doesn't match any jira account`, assignee.JiraEmail))
+		case len(users) > 1:
+			checks = append(checks, failCheck(`assignee "%s" matches more than one jira account`, assignee.JiraEmail))
+		default:
+			checks = append(checks, okCheck(`assignee "%s" resolves to jira account %s`, assignee.JiraEmail, users[0].AccountID))
+		}
+	}
+	return checks
+}
+
+// printChecks prints a line per check, followed by its hint if any, and
+// returns the number of failed checks.
+func printChecks(checks []configCheck) int {
+	failed := 0
+	for _, check := range checks {
+		fmt.Printf("  %-4s  %s\n", check.Status, check.Message)
+		if check.Hint != "" {
+			fmt.Printf("        hint: %s\n", check.Hint)
+		}
+		if check.Status == CHECK_FAIL {
+			failed++
+		}
+	}
+	return failed
+}
+
+func (c configCheck) withHint(format string, a ...any) configCheck {
+	c.Hint = fmt.Sprintf(format, a...)
+	return c
+}
+
+func okCheck(format string, a ...any) configCheck {
+	return configCheck{Status: CHECK_OK, Message: fmt.Sprintf(format, a...)}
+}
+
+func warnCheck(format string, a ...any) configCheck {
+	return configCheck{Status: CHECK_WARN, Message: fmt.Sprintf(format, a...)}
+}
+
+func failCheck(format string, a ...any) configCheck {
+	return configCheck{Status: CHECK_FAIL, Message: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+type DbCmd struct {
+	Migrate *DbMigrateCmd `arg:"subcommand:migrate" help:"apply the pending local storage schema migrations"`
+}
+
+type DbMigrateCmd struct {
+	Config *string `arg:"--config,-c" help:"path to config file, only used for its database path" placeholder:"<PATH>"`
+	Status bool    `arg:"--status" help:"list the migrations and whether they are applied, without applying them"`
+}
+
+type DatabaseConfig struct {
+	Path    *string `yaml:"path"`
+	Backend *string `yaml:"backend"`
+}
+
+func (c DatabaseConfig) validate() error {
+	if c.Backend == nil {
+		return nil
+	}
+	switch models.Backend(*c.Backend) {
+	case models.BACKEND_SQLITE, models.BACKEND_SQLITE_CGO:
+		return nil
+	default:
+		return fmt.Errorf(`"database.backend" property should be one of [%s, %s]`, models.BACKEND_SQLITE, models.BACKEND_SQLITE_CGO)
+	}
+}
+
+// getDatabaseBackend returns the configured local storage backend.
+func getDatabaseBackend(config Config) models.Backend {
+	if config.Database != nil && config.Database.Backend != nil {
+		return models.Backend(*config.Database.Backend)
+	}
+	return models.DEFAULT_BACKEND
+}
+
+// getDatabasePath returns the local storage location, the "--db" flag takes
+// precedence over the "database.path" option. Relative "database.path"
+// options are resolved from the config file directory.
+func getDatabasePath(args Cmd, configPath string, config Config) string {
+	if args.DB != nil && *args.DB != "" {
+		return *args.DB
+	}
+	if config.Database != nil && config.Database.Path != nil && *config.Database.Path != "" {
+		path := *config.Database.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(configPath), path)
+		}
+		return path
+	}
+	return models.DEFAULT_DB_PATH
+}
+
+// initializeModels opens the local storage and applies its pending
+// migrations.
+func initializeModels(args Cmd, configPath string, config Config, log *logrus.Logger) (*models.Models, error) {
+	path := getDatabasePath(args, configPath, config)
+	backend := getDatabaseBackend(config)
+	log.WithFields(logrus.Fields{"db": path, "backend": backend}).Debugln("initializing db models")
+	m, err := models.Initialize(backend, path)
+	if err != nil {
+		log.WithFields(logrus.Fields{"db": path, "backend": backend, "err": err}).Errorln("db models initialization failed")
+		return nil, err
+	}
+	return m, nil
+}
+
+// openModelsReadOnly opens the existing local storage without creating it
+// nor applying its migrations, it's meant for the commands that must not
+// change anything.
+func openModelsReadOnly(args Cmd, configPath string, config Config, log *logrus.Logger) (*models.Models, error) {
+	path := getDatabasePath(args, configPath, config)
+	backend := getDatabaseBackend(config)
+	log.WithFields(logrus.Fields{"db": path, "backend": backend}).Debugln("opening read-only db models")
+	m, err := models.OpenReadOnly(backend, path)
+	if errors.Is(err, models.ErrNotMigrated) {
+		err = fmt.Errorf(`%w, run "db migrate" first`, err)
+	}
+	if err != nil {
+		log.WithFields(logrus.Fields{"db": path, "backend": backend, "err": err}).Errorln("opening read-only db models failed")
+		return nil, err
+	}
+	return m, nil
+}
+
+// openModelsForPlan opens the local storage read-only. A local storage that
+// doesn't exist or is not migrated is planned as an empty one, so a new
+// config can be previewed before its first sync.
+func openModelsForPlan(args Cmd, configPath string, config Config, log *logrus.Logger) (*models.Models, error) {
+	path := getDatabasePath(args, configPath, config)
+	backend := getDatabaseBackend(config)
+	log.WithFields(logrus.Fields{"db": path, "backend": backend}).Debugln("opening read-only db models")
+	m, err := models.OpenReadOnly(backend, path)
+	if errors.Is(err, models.ErrNotMigrated) {
+		log.WithFields(logrus.Fields{"db": path, "backend": backend, "err": err}).Warnln("local storage is not usable as it is, planning as if it was empty")
+		m, err = models.InitializeInMemory(backend)
+	}
+	if err != nil {
+		log.WithFields(logrus.Fields{"db": path, "backend": backend, "err": err}).Errorln("opening read-only db models failed")
+		return nil, err
+	}
+	return m, nil
+}
+
+// DbMigrateAction applies the pending local storage migrations, or lists
+// them when "--status" is given. Keep in mind every command applies them at
+// startup, this one is meant for upgrades done ahead of time.
+func DbMigrateAction(args Cmd) {
+	if args.Db == nil || args.Db.Migrate == nil {
+		exitOnInvalidCall("db migrate")
+	}
+	cmd := args.Db.Migrate
+
+	level := logrus.InfoLevel
+	if args.Debug != nil && *args.Debug == true {
+		level = logrus.DebugLevel
+	}
+	log := newLogger(level)
+
+	var config Config
+	configPath := ""
+	if cmd.Config != nil {
+		var err error
+		configPath = *cmd.Config
+		config, err = readConfig(configPath, log)
+		if err != nil {
+			exitFromErr(err)
+		}
+	}
+	path := getDatabasePath(args, configPath, config)
+
+	if !cmd.Status {
+		m, err := initializeModels(args, configPath, config, log)
+		if err != nil {
+			exitFromErr(err)
+		}
+		m.Close()
+	}
+
+	statuses, err := models.GetMigrationsStatus(getDatabaseBackend(config), path)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	fmt.Printf("database: %s\n", path)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tDESCRIPTION\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Description, appliedAt)
+	}
+	tw.Flush()
+}
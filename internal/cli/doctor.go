@@ -0,0 +1,358 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	jira "github.com/ctreminiom/go-atlassian/jira/v3"
+	jiramodels "github.com/ctreminiom/go-atlassian/pkg/infra/models"
+	"github.com/iolave/jira-tickets-from-gh/internal/github"
+	"github.com/sirupsen/logrus"
+)
+
+// GITHUB_TOKEN_SCOPES are the classic token scopes a sync project needs.
+var GITHUB_TOKEN_SCOPES = []string{"project", "read:org", "repo"}
+
+// JIRA_PROJECT_PERMISSIONS are the jira project permissions a sync project
+// needs.
+var JIRA_PROJECT_PERMISSIONS = []string{"BROWSE_PROJECTS", "CREATE_ISSUES", "TRANSITION_ISSUES"}
+
+const (
+	GITHUB_TOKENS_URL = "https://github.com/settings/tokens"
+	JIRA_TOKENS_URL   = "https://id.atlassian.com/manage-profile/security/api-tokens"
+)
+
+type DoctorCmd struct {
+	Config  string  `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project *string `arg:"--project" help:"only diagnose the given sync project" placeholder:"<NAME>"`
+}
+
+// DoctorAction checks, for every sync project, how its secrets are wired and
+// that its GitHub and Jira credentials have the access the sync needs,
+// printing a hint for every failure. It exits with 1 if any check fails.
+func DoctorAction(args Cmd) {
+	if args.Doctor == nil {
+		exitOnInvalidCall("doctor")
+	}
+	cmd := args.Doctor
+
+	level := logrus.InfoLevel
+	if args.Debug != nil && *args.Debug == true {
+		level = logrus.DebugLevel
+	}
+	log := newLogger(level)
+
+	config, err := readConfig(cmd.Config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	projects := []int{}
+	for i := range config.Projects {
+		if cmd.Project == nil || config.Projects[i].Name == *cmd.Project {
+			projects = append(projects, i)
+		}
+	}
+	if cmd.Project != nil && len(projects) == 0 {
+		exitFromErr(fmt.Errorf(`sync project "%s" not found in config`, *cmd.Project))
+	}
+
+	if level != logrus.DebugLevel {
+		log.SetOutput(io.Discard)
+	}
+
+	failed := 0
+	for _, projPos := range projects {
+		fmt.Printf("project \"%s\":\n", config.Projects[projPos].Name)
+		failed += printChecks(diagnoseProject(context.Background(), args, config, projPos))
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d checks failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("no problems found")
+}
+
+// diagnoseProject runs the checks of a sync project, the GitHub and Jira
+// checks are skipped when their secrets are missing.
+func diagnoseProject(ctx context.Context, args Cmd, config Config, projPos int) []configCheck {
+	projectCfg := config.Projects[projPos]
+
+	ghSecrets := checkGithubSecrets(args, projectCfg.Name)
+	jiraSecrets := checkJiraSecrets(args, projectCfg.Name)
+	checks := append(slices.Clone(ghSecrets), jiraSecrets...)
+
+	if !hasFailedCheck(ghSecrets) {
+		checks = append(checks, diagnoseGithub(ctx, args, config, projPos)...)
+	}
+	if !hasFailedCheck(jiraSecrets) {
+		checks = append(checks, diagnoseJira(ctx, args, config, projPos)...)
+	}
+
+	return checks
+}
+
+// checkGithubSecrets checks where the project GitHub token, or GitHub App
+// credentials, come from.
+func checkGithubSecrets(args Cmd, projectName string) []configCheck {
+	creds, err := getProjectGithubCreds(args, projectName)
+	if errors.Is(err, errIncompleteGithubApp) {
+		return []configCheck{failCheck("%s", err).
+			withHint("set the app id, installation id and private key env variables, or none of them")}
+	}
+	if err != nil || !creds.isApp() {
+		return checkSecret("github token", []string{"GITHUB_TOKEN_" + projectName, "GITHUB_TOKEN"}, args.GithubToken)
+	}
+
+	checks := []configCheck{}
+	labels := []string{"github app id", "github app installation id", "github app private key"}
+	for i, env := range creds.Envs {
+		checks = append(checks, checkSecret(labels[i], []string{env}, nil)...)
+	}
+	return checks
+}
+
+// checkJiraSecrets checks where the project Jira credentials come from, the
+// project ones are only used when both of them are set.
+func checkJiraSecrets(args Cmd, projectName string) []configCheck {
+	envEmail, envToken := "JIRA_EMAIL_"+projectName, "JIRA_TOKEN_"+projectName
+	emailSet, tokenSet := isSecretSet(envEmail), isSecretSet(envToken)
+
+	if emailSet && tokenSet {
+		return append(
+			checkSecret("jira email", []string{envEmail}, nil),
+			checkSecret("jira token", []string{envToken}, nil)...,
+		)
+	}
+
+	checks := []configCheck{}
+	if emailSet || tokenSet {
+		set, missing := envEmail, envToken
+		if tokenSet {
+			set, missing = envToken, envEmail
+		}
+		checks = append(checks, warnCheck(`"%s" is ignored because "%s" is not set, the global jira credentials are used`, set, missing).
+			withHint(`set both "%s" and "%s", or their "%s" variants`, envEmail, envToken, SECRET_FILE_SUFFIX))
+	}
+	checks = append(checks, checkSecret("jira email", []string{"JIRA_EMAIL"}, args.JiraEmail)...)
+	checks = append(checks, checkSecret("jira token", []string{"JIRA_TOKEN"}, args.JiraToken)...)
+
+	return checks
+}
+
+// checkSecret checks the env variables a secret is read from, in order of
+// precedence. The value of a flag is used when none of them is set.
+func checkSecret(label string, names []string, flag *string) []configCheck {
+	checks := []configCheck{}
+	for _, name := range names {
+		value, valueOk := os.LookupEnv(name)
+		path, pathOk := os.LookupEnv(name + SECRET_FILE_SUFFIX)
+		valueOk, pathOk = valueOk && value != "", pathOk && path != ""
+
+		switch {
+		case valueOk && pathOk:
+			checks = append(checks, warnCheck(`%s: "%s%s" is ignored because "%s" is set`, label, name, SECRET_FILE_SUFFIX, name).
+				withHint(`unset one of them`))
+			fallthrough
+		case valueOk:
+			return append(checks, checkSecretValue(label, value, fmt.Sprintf(`the "%s" env variable`, name)))
+		case pathOk:
+			b, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				return append(checks, failCheck(`%s: "%s%s" points to "%s", which doesn't exist`, label, name, SECRET_FILE_SUFFIX, path).
+					withHint(`with docker compose, add the secret to the service "secrets" list and to the top level "secrets" section`))
+			}
+			if err != nil {
+				return append(checks, failCheck(`%s: reading "%s" failed: %s`, label, path, err).
+					withHint(`make sure the file is readable by the user the program runs as`))
+			}
+			value := strings.TrimRight(string(b), "\r\n")
+			if value == "" {
+				return append(checks, failCheck(`%s: "%s" is empty`, label, path).
+					withHint(`with docker compose, make sure the env variable the secret is created from is set when running "docker compose up"`))
+			}
+			return append(checks, checkSecretValue(label, value, fmt.Sprintf(`"%s" (through "%s%s")`, path, name, SECRET_FILE_SUFFIX)))
+		}
+	}
+
+	if flag != nil && *flag != "" {
+		return append(checks, checkSecretValue(label, *flag, "a cli flag"))
+	}
+
+	return append(checks, failCheck(`%s is not set`, label).
+		withHint(`set one of [%s] or their "%s" variants`, strings.Join(names, ", "), SECRET_FILE_SUFFIX))
+}
+
+// checkSecretValue warns about values that were likely copied with extra
+// characters.
+func checkSecretValue(label, value, source string) configCheck {
+	if strings.TrimSpace(value) != value || strings.Trim(value, `"'`) != value {
+		return warnCheck(`%s read from %s has surrounding spaces or quotes`, label, source).
+			withHint(`remove them, values are used as they are`)
+	}
+	return okCheck(`%s read from %s`, label, source)
+}
+
+// isSecretSet reports whether an env variable or its "_FILE" variant is set.
+func isSecretSet(name string) bool {
+	return os.Getenv(name) != "" || os.Getenv(name+SECRET_FILE_SUFFIX) != ""
+}
+
+// diagnoseGithub checks the project GitHub token scopes and its access to
+// the GitHub project.
+func diagnoseGithub(ctx context.Context, args Cmd, config Config, projPos int) []configCheck {
+	projectCfg := config.Projects[projPos]
+	creds, err := getProjectGithubCreds(args, projectCfg.Name)
+	if err != nil {
+		return []configCheck{failCheck("github token: %s", err)}
+	}
+	gh, err := creds.newClient()
+	if err != nil {
+		return []configCheck{failCheck("github app: %s", err).
+			withHint("use the PEM private key generated in the app settings")}
+	}
+	if creds.isApp() {
+		return diagnoseGithubApp(ctx, gh, creds, projectCfg.Github.ProjectID)
+	}
+
+	viewer, res, err := gh.GetViewer(ctx)
+	if res != nil && res.StatusCode == http.StatusUnauthorized {
+		return []configCheck{failCheck("github token rejected").
+			withHint("the token is invalid, expired or revoked, create a new one at %s", GITHUB_TOKENS_URL)}
+	}
+	if err != nil {
+		return []configCheck{failCheck("github token check failed: %s", err).
+			withHint("make sure api.github.com is reachable")}
+	}
+
+	checks := []configCheck{okCheck("github token belongs to %s", viewer.Data.Viewer.Login)}
+	checks = append(checks, checkGithubScopes(res))
+
+	if _, _, err := gh.GetProjectFields(ctx, projectCfg.Github.ProjectID); err != nil {
+		return append(checks, failCheck("github project %s not accessible: %s", projectCfg.Github.ProjectID, err).
+			withHint(`check the project id (see "github list-projects"), that %s can see the project and, for organizations using SAML SSO, that the token is authorized for the organization`, viewer.Data.Viewer.Login))
+	}
+	return append(checks, okCheck("github project %s is accessible", projectCfg.Github.ProjectID))
+}
+
+// diagnoseGithubApp checks that an installation token can be minted for the
+// GitHub App and that the installation can access the GitHub project.
+func diagnoseGithubApp(ctx context.Context, gh *github.GitHubClient, creds githubCreds, projectId string) []configCheck {
+	if _, err := gh.Token(ctx); err != nil {
+		return []configCheck{failCheck("github app installation token could not be created: %s", err).
+			withHint("check the app id, that the private key belongs to the app and that the installation id is the one of the account owning the project")}
+	}
+	checks := []configCheck{okCheck("github app %d installation %d token created", creds.AppID, creds.InstallationID)}
+
+	if _, _, err := gh.GetProjectFields(ctx, projectId); err != nil {
+		return append(checks, failCheck("github project %s not accessible: %s", projectId, err).
+			withHint(`check the project id (see "github list-projects") and that the app has read and write access to projects and read access to issues and pull requests`))
+	}
+	return append(checks, okCheck("github project %s is accessible", projectId))
+}
+
+// checkGithubScopes checks the scopes of a classic token, fine-grained
+// tokens don't report them.
+func checkGithubScopes(res *http.Response) configCheck {
+	scopes, ok := github.GetTokenScopes(res)
+	if !ok {
+		return warnCheck("github token scopes not reported (fine-grained token), only the project access is checked").
+			withHint("make sure the token has read and write access to projects and read access to the repositories issues and pull requests")
+	}
+
+	missing := []string{}
+	for _, scope := range GITHUB_TOKEN_SCOPES {
+		if !hasGithubScope(scopes, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return failCheck("github token is missing the [%s] scopes, it has [%s]", strings.Join(missing, ", "), strings.Join(scopes, ", ")).
+			withHint("add the missing scopes to the token at %s", GITHUB_TOKENS_URL)
+	}
+	return okCheck("github token has the [%s] scopes", strings.Join(GITHUB_TOKEN_SCOPES, ", "))
+}
+
+// hasGithubScope reports whether the scopes grant the given one, either
+// directly or through a broader scope.
+func hasGithubScope(scopes []string, scope string) bool {
+	if slices.Contains(scopes, scope) {
+		return true
+	}
+	return scope == "read:org" && (slices.Contains(scopes, "write:org") || slices.Contains(scopes, "admin:org"))
+}
+
+// diagnoseJira checks the project Jira credentials and the permissions they
+// have on the Jira project.
+func diagnoseJira(ctx context.Context, args Cmd, config Config, projPos int) []configCheck {
+	projectCfg := config.Projects[projPos]
+	jc, err := newProjectJiraClient(args, config, projPos)
+	if err != nil {
+		return []configCheck{failCheck("jira client: %s", err).
+			withHint(`check the "jira.subdomain" property`)}
+	}
+
+	me, _, err := jc.MySelf.Details(ctx, nil)
+	switch {
+	case errors.Is(err, jiramodels.ErrUnauthorized):
+		return []configCheck{failCheck("jira credentials rejected by %s.atlassian.net", projectCfg.Jira.Subdomain).
+			withHint("check that the jira email and token belong to the same Atlassian account, tokens are created at %s", JIRA_TOKENS_URL)}
+	case err != nil:
+		return []configCheck{failCheck("jira credentials check failed: %s", err).
+			withHint(`check the "jira.subdomain" property and that %s.atlassian.net is reachable`, projectCfg.Jira.Subdomain)}
+	}
+	checks := []configCheck{okCheck("jira credentials work, authenticated as %s (%s)", me.EmailAddress, me.AccountID)}
+
+	return append(checks, checkJiraPermissions(ctx, jc, projectCfg.Jira.ProjectKey)...)
+}
+
+// checkJiraPermissions checks that the authenticated account has the
+// permissions the sync needs on the jira project.
+func checkJiraPermissions(ctx context.Context, jc *jira.Client, projectKey string) []configCheck {
+	project, _, err := jc.Project.Get(ctx, projectKey, nil)
+	if err != nil {
+		return []configCheck{failCheck(`jira project "%s" not found: %s`, projectKey, err).
+			withHint(`check the "jira.projectKey" property and that the account has the BROWSE_PROJECTS permission on the project`)}
+	}
+	projectId, err := strconv.Atoi(project.ID)
+	if err != nil {
+		return []configCheck{failCheck(`jira project "%s" has an unexpected id "%s"`, projectKey, project.ID)}
+	}
+
+	payload := &jiramodels.PermissionCheckPayload{
+		ProjectPermissions: []*jiramodels.BulkProjectPermissionsScheme{
+			{Projects: []int{projectId}, Permissions: JIRA_PROJECT_PERMISSIONS},
+		},
+	}
+	grants, _, err := jc.Permission.Check(ctx, payload)
+	if err != nil {
+		return []configCheck{failCheck(`jira project "%s" permissions check failed: %s`, projectKey, err)}
+	}
+
+	checks := []configCheck{}
+	for _, permission := range JIRA_PROJECT_PERMISSIONS {
+		granted := slices.ContainsFunc(grants.ProjectPermissions, func(g *jiramodels.ProjectPermissionGrantsScheme) bool {
+			return g.Permission == permission && slices.Contains(g.Projects, projectId)
+		})
+		if !granted {
+			checks = append(checks, failCheck(`jira account lacks the %s permission on project "%s"`, permission, projectKey).
+				withHint(`grant it to the account, or to one of its groups or project roles, in the permission scheme of the project (Project settings > Permissions)`))
+			continue
+		}
+		checks = append(checks, okCheck(`jira account has the %s permission on project "%s"`, permission, projectKey))
+	}
+
+	return checks
+}
+
+func hasFailedCheck(checks []configCheck) bool {
+	return slices.ContainsFunc(checks, func(c configCheck) bool { return c.Status == CHECK_FAIL })
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SECRET_FILE_SUFFIX is appended to an env variable name to read its value
+// from the file the variable points to instead (i.e. docker secrets).
+const SECRET_FILE_SUFFIX = "_FILE"
+
+// envReferenceRegex matches "$$", "${VAR}" and "${VAR:-default}".
+var envReferenceRegex = regexp.MustCompile(`\$\$|\$\{([a-zA-Z_][a-zA-Z0-9_]*)(:-([^}]*))?\}`)
+
+// lookupEnv retrieves the value of an env variable, or the content of the
+// file its "_FILE" variant points to when it is not set.
+func lookupEnv(name string) (string, bool, error) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true, nil
+	}
+	return readSecretFile(name)
+}
+
+// readSecretFile reads the file the "_FILE" variant of an env variable
+// points to, trailing line breaks are dropped.
+func readSecretFile(name string) (string, bool, error) {
+	path, ok := os.LookupEnv(name + SECRET_FILE_SUFFIX)
+	if !ok || path == "" {
+		return "", false, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf(`reading "%s%s" failed: %w`, name, SECRET_FILE_SUFFIX, err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), true, nil
+}
+
+// readSecretFiles sets the tokens and emails that were not given as flags or
+// env variables from the files their "_FILE" env variables point to. Every
+// secret is read even if a previous one failed.
+func readSecretFiles(args *Cmd) error {
+	secrets := []struct {
+		env   string
+		value **string
+	}{
+		{"GITHUB_TOKEN", &args.GithubToken},
+		{"JIRA_EMAIL", &args.JiraEmail},
+		{"JIRA_TOKEN", &args.JiraToken},
+		{"API_TOKEN", &args.APIToken},
+	}
+
+	var errs []error
+	for _, secret := range secrets {
+		if *secret.value != nil {
+			continue
+		}
+		value, ok, err := readSecretFile(secret.env)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			*secret.value = &value
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// interpolateConfigEnv replaces the env variables references within the
+// scalar values of a parsed config, keys and comments are left as they are.
+// Unquoted values are resolved again so "${PORT}" can still hold a number.
+func interpolateConfigEnv(node *yaml.Node) error {
+	missing := []string{}
+	if err := interpolateNodeEnv(node, &missing); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("config references the unset env variables [%s]", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func interpolateNodeEnv(node *yaml.Node, missing *[]string) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := interpolateNodeEnv(child, missing); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := interpolateNodeEnv(node.Content[i], missing); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		value, err := interpolateEnv(node.Value, missing)
+		if err != nil {
+			return err
+		}
+		if value == node.Value {
+			return nil
+		}
+		node.Value = value
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+	return nil
+}
+
+// interpolateEnv replaces the "${VAR}" and "${VAR:-default}" references
+// within a config value with the env variables values, the default is used
+// when the variable is unset or empty. "$$" escapes a "$". The unset
+// variables without a default are appended to missing.
+func interpolateEnv(s string, missing *[]string) (string, error) {
+	var err error
+
+	result := envReferenceRegex.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+
+		groups := envReferenceRegex.FindStringSubmatch(match)
+		name, hasDefault := groups[1], len(groups[2]) > 0
+		value, ok, lookupErr := lookupEnv(name)
+		if lookupErr != nil && err == nil {
+			err = lookupErr
+		}
+		switch {
+		case ok && value != "":
+			return value
+		case hasDefault:
+			return groups[3]
+		case !ok && !slices.Contains(*missing, name):
+			*missing = append(*missing, name)
+		}
+		return value
+	})
+
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
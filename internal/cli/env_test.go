@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestInterpolateEnv(t *testing.T) {
+	t.Setenv("ENV_TEST_SET", "value")
+	t.Setenv("ENV_TEST_EMPTY", "")
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		missing []string
+	}{
+		{name: "plain value", value: "value", want: "value"},
+		{name: "set variable", value: "${ENV_TEST_SET}", want: "value"},
+		{name: "variable within text", value: "a-${ENV_TEST_SET}-b", want: "a-value-b"},
+		{name: "escaped dollar", value: "$$", want: "$"},
+		{name: "escaped reference", value: "$${ENV_TEST_SET}", want: "${ENV_TEST_SET}"},
+		{name: "lone dollar", value: "a$b", want: "a$b"},
+		{name: "default of a set variable", value: "${ENV_TEST_SET:-default}", want: "value"},
+		{name: "default of an unset variable", value: "${ENV_TEST_UNSET:-default}", want: "default"},
+		{name: "default of an empty variable", value: "${ENV_TEST_EMPTY:-default}", want: "default"},
+		{name: "empty default", value: "${ENV_TEST_UNSET:-}", want: ""},
+		{name: "empty variable", value: "${ENV_TEST_EMPTY}", want: ""},
+		{name: "missing variable", value: "${ENV_TEST_UNSET}", want: "", missing: []string{"ENV_TEST_UNSET"}},
+		{
+			name:    "missing variables are reported once",
+			value:   "${ENV_TEST_UNSET}${ENV_TEST_OTHER}${ENV_TEST_UNSET}",
+			want:    "",
+			missing: []string{"ENV_TEST_UNSET", "ENV_TEST_OTHER"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing := []string{}
+			got, err := interpolateEnv(tt.value, &missing)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if tt.missing == nil {
+				tt.missing = []string{}
+			}
+			if !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("got missing %v, want %v", missing, tt.missing)
+			}
+		})
+	}
+}
+
+func TestInterpolateConfigEnv(t *testing.T) {
+	t.Setenv("ENV_TEST_NUMBER", "10")
+	t.Setenv("ENV_TEST_YAML", "a: b # c")
+
+	tests := []struct {
+		name    string
+		content string
+		want    any
+		err     string
+	}{
+		{
+			name:    "unquoted values are resolved again",
+			content: "n: ${ENV_TEST_NUMBER}",
+			want:    map[string]any{"n": 10},
+		},
+		{
+			name:    "quoted values stay strings",
+			content: `n: "${ENV_TEST_NUMBER}"`,
+			want:    map[string]any{"n": "10"},
+		},
+		{
+			name:    "values can hold yaml special characters",
+			content: "s: ${ENV_TEST_YAML}",
+			want:    map[string]any{"s": "a: b # c"},
+		},
+		{
+			name:    "keys and comments are left as they are",
+			content: "# ${ENV_TEST_UNSET}\n${ENV_TEST_NUMBER}:\n  - ${ENV_TEST_NUMBER}",
+			want:    map[string]any{"${ENV_TEST_NUMBER}": []any{10}},
+		},
+		{
+			name:    "missing variables fail",
+			content: "a: ${ENV_TEST_UNSET}\nb: ${ENV_TEST_OTHER:-}",
+			err:     "config references the unset env variables [ENV_TEST_UNSET]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.content), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			err := interpolateConfigEnv(&doc)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got any
+			if err := doc.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
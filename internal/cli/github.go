@@ -1,10 +1,15 @@
 package cli
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/iolave/jira-tickets-from-gh/internal/github"
 )
@@ -37,9 +42,10 @@ func GithubProjectListAction(args Cmd) {
 		exitOnConflictingFlags("--org", "--user")
 	}
 
+	ctx := context.Background()
 	gh := github.New(*args.GithubToken)
 	if args.Github.ListProject.User != nil {
-		result, _, err := gh.ListUserProjects(*args.Github.ListProject.User)
+		result, _, err := gh.ListUserProjects(ctx, *args.Github.ListProject.User)
 		if err != nil {
 			exitFromErr(err)
 		}
@@ -50,7 +56,7 @@ func GithubProjectListAction(args Cmd) {
 		fmt.Println(string(b))
 		os.Exit(0)
 	} else {
-		result, _, err := gh.ListOrganizationProjects(*args.Github.ListProject.Org)
+		result, _, err := gh.ListOrganizationProjects(ctx, *args.Github.ListProject.Org)
 		if err != nil {
 			exitFromErr(err)
 		}
@@ -62,3 +68,155 @@ func GithubProjectListAction(args Cmd) {
 		os.Exit(0)
 	}
 }
+
+// env variables of the GitHub App credentials, suffixed with "_{name}" for
+// project specific ones
+const (
+	GITHUB_APP_ID_ENV              = "GITHUB_APP_ID"
+	GITHUB_APP_INSTALLATION_ID_ENV = "GITHUB_APP_INSTALLATION_ID"
+	GITHUB_APP_PRIVATE_KEY_ENV     = "GITHUB_APP_PRIVATE_KEY"
+)
+
+// errIncompleteGithubApp is returned when only some of the GitHub App
+// credentials env variables are set.
+var errIncompleteGithubApp = errors.New("github app credentials are incomplete")
+
+// githubCreds are the GitHub credentials of a sync project, either a token or
+// the credentials of a GitHub App installation.
+type githubCreds struct {
+	Token          string
+	AppID          int64
+	InstallationID int64
+	PrivateKey     string
+	Envs           []string // env variables the credentials were read from
+}
+
+func (c githubCreds) isApp() bool {
+	return c.Token == ""
+}
+
+// id identifies the credentials, projects with the same credentials share
+// their client.
+func (c githubCreds) id() string {
+	if !c.isApp() {
+		return "token:" + c.Token
+	}
+	return fmt.Sprintf("app:%d:%d:%x", c.AppID, c.InstallationID, sha256.Sum256([]byte(c.PrivateKey)))
+}
+
+func (c githubCreds) newClient() (*github.GitHubClient, error) {
+	if !c.isApp() {
+		return github.New(c.Token), nil
+	}
+	return github.NewApp(c.AppID, c.InstallationID, []byte(c.PrivateKey))
+}
+
+// getProjectGithubCreds returns the GitHub credentials of a sync project.
+// Project specific credentials ("GITHUB_TOKEN_{name}" and then the
+// "GITHUB_APP_*_{name}" ones) take precedence over the global ones, and
+// tokens over GitHub App credentials.
+func getProjectGithubCreds(args Cmd, projectName string) (githubCreds, error) {
+	suffix := "_" + projectName
+	envToken := "GITHUB_TOKEN" + suffix
+
+	token, _, err := lookupEnv(envToken)
+	if err != nil {
+		return githubCreds{}, err
+	}
+	if token != "" {
+		return githubCreds{Token: token, Envs: []string{envToken}}, nil
+	}
+	if creds, ok, err := getGithubAppCreds(suffix); err != nil || ok {
+		return creds, err
+	}
+
+	if args.GithubToken != nil && *args.GithubToken != "" {
+		return githubCreds{Token: *args.GithubToken, Envs: []string{"GITHUB_TOKEN"}}, nil
+	}
+	if creds, ok, err := getGithubAppCreds(""); err != nil || ok {
+		return creds, err
+	}
+
+	return githubCreds{}, fmt.Errorf(`please set the "GITHUB_TOKEN" or "%s" env variable, or the GitHub App credentials`, envToken)
+}
+
+// getGithubAppCreds reads the GitHub App credentials env variables with the
+// given suffix, it fails when only some of them are set.
+func getGithubAppCreds(suffix string) (githubCreds, bool, error) {
+	envs := []string{GITHUB_APP_ID_ENV + suffix, GITHUB_APP_INSTALLATION_ID_ENV + suffix, GITHUB_APP_PRIVATE_KEY_ENV + suffix}
+	values := make([]string, len(envs))
+	missing := []string{}
+	for i, env := range envs {
+		value, _, err := lookupEnv(env)
+		if err != nil {
+			return githubCreds{}, false, err
+		}
+		if value == "" {
+			missing = append(missing, env)
+		}
+		values[i] = value
+	}
+
+	if len(missing) == len(envs) {
+		return githubCreds{}, false, nil
+	}
+	if len(missing) > 0 {
+		return githubCreds{}, false, fmt.Errorf("%w, the [%s] env variables are missing", errIncompleteGithubApp, strings.Join(missing, ", "))
+	}
+
+	var err error
+	creds := githubCreds{PrivateKey: values[2], Envs: envs}
+	if creds.AppID, err = strconv.ParseInt(values[0], 10, 64); err != nil {
+		return githubCreds{}, false, fmt.Errorf(`"%s" env variable should be a number`, envs[0])
+	}
+	if creds.InstallationID, err = strconv.ParseInt(values[1], 10, 64); err != nil {
+		return githubCreds{}, false, fmt.Errorf(`"%s" env variable should be a number`, envs[1])
+	}
+
+	return creds, true, nil
+}
+
+// newProjectGithubClient creates a GitHub client for a sync project using
+// the project credentials.
+func newProjectGithubClient(args Cmd, projectName string) (*github.GitHubClient, error) {
+	creds, err := getProjectGithubCreds(args, projectName)
+	if err != nil {
+		return nil, err
+	}
+	return creds.newClient()
+}
+
+// githubClients shares a GitHub client per credentials among the sync
+// projects, so the rate limit of each token or app installation is tracked
+// by a single client.
+type githubClients struct {
+	args Cmd
+
+	mu      sync.Mutex
+	clients map[string]*github.GitHubClient // by credentials id
+}
+
+func newGithubClients(args Cmd) *githubClients {
+	return &githubClients{args: args, clients: map[string]*github.GitHubClient{}}
+}
+
+// get returns the client of the sync project credentials. Credentials are
+// looked up on every call so a rotated "_FILE" secret is picked up.
+func (c *githubClients) get(projectName string) (*github.GitHubClient, error) {
+	creds, err := getProjectGithubCreds(c.args, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := creds.id()
+	gh, ok := c.clients[id]
+	if !ok {
+		if gh, err = creds.newClient(); err != nil {
+			return nil, err
+		}
+		c.clients[id] = gh
+	}
+	return gh, nil
+}
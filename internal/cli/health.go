@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DEFAULT_READINESS_FACTOR      = 3
+	READINESS_CREDENTIALS_TTL     = 5 * time.Minute
+	READINESS_CREDENTIALS_TIMEOUT = 10 * time.Second
+)
+
+type HealthcheckCmd struct {
+	Config  string        `arg:"--config,-c" default:"./config.yml" help:"path to the config file of the running sync, used to find the health endpoint" placeholder:"<PATH>"`
+	URL     *string       `arg:"--url" help:"health endpoint to check, overrides the one found in the config" placeholder:"<URL>"`
+	Timeout time.Duration `arg:"--timeout" default:"5s" help:"time to wait for the health endpoint" placeholder:"<DURATION>"`
+}
+
+// HealthcheckAction calls a health endpoint of a running sync and exits
+// with 0 if it's healthy or 1 otherwise, so docker can check the container
+// health without curl. Without --url, the readiness endpoint is found from
+// the config "metricsAddress", and the check passes when "enableHealth" is
+// not set as there's nothing to check.
+func HealthcheckAction(args Cmd) {
+	if args.Healthcheck == nil {
+		exitOnInvalidCall("healthcheck")
+	}
+
+	url := ""
+	if args.Healthcheck.URL != nil {
+		url = *args.Healthcheck.URL
+	} else {
+		log := newLogger(logrus.InfoLevel)
+		log.SetOutput(io.Discard)
+		config, err := readConfig(args.Healthcheck.Config, log)
+		if err != nil {
+			exitFromErr(err)
+		}
+		if config.EnableHealth == nil || !*config.EnableHealth {
+			fmt.Println(`health endpoints disabled, set "enableHealth" to check them`)
+			os.Exit(0)
+		}
+		address := DEFAULT_METRICS_ADDRESS
+		if config.MetricsAddress != nil {
+			address = *config.MetricsAddress
+		}
+		url = getReadinessURL(address)
+	}
+
+	client := &http.Client{Timeout: args.Healthcheck.Timeout}
+	res, err := client.Get(url)
+	if err != nil {
+		exitFromErr(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		exitFromErr(fmt.Errorf(`health endpoint responded with status %d`, res.StatusCode))
+	}
+	os.Exit(0)
+}
+
+// getReadinessURL returns the readiness endpoint url of a server listening on
+// the given address, addresses listening on every interface are reached
+// through the loopback one.
+func getReadinessURL(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Sprintf("http://%s/readyz", address)
+	}
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return fmt.Sprintf("http://%s/readyz", net.JoinHostPort(host, port))
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+type readinessResponse struct {
+	Ready    bool                       `json:"ready"`
+	Projects []projectReadinessResponse `json:"projects"`
+}
+
+type projectReadinessResponse struct {
+	Name   string  `json:"name"`
+	Ready  bool    `json:"ready"`
+	Reason *string `json:"reason"`
+}
+
+type credentialsCheck struct {
+	checkedAt time.Time
+	version   int // config version the check was made with
+	err       error
+}
+
+// healthChecker answers the health endpoints, credentials checks are cached
+// for READINESS_CREDENTIALS_TTL so probes don't spend the api rate limits.
+type healthChecker struct {
+	args  Cmd
+	state *syncState
+	ghs   *githubClients
+
+	mu     sync.Mutex
+	checks map[string]credentialsCheck // by project name
+}
+
+func newHealthChecker(args Cmd, state *syncState, ghs *githubClients) *healthChecker {
+	return &healthChecker{
+		args:   args,
+		state:  state,
+		ghs:    ghs,
+		checks: map[string]credentialsCheck{},
+	}
+}
+
+// liveness responds ok as long as the process is able to serve requests.
+func (h *healthChecker) liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// readiness responds ok when every project finished a sync cycle within
+// "readinessFactor" times its interval and its credentials still work.
+func (h *healthChecker) readiness(w http.ResponseWriter, r *http.Request) {
+	result := readinessResponse{Ready: true, Projects: []projectReadinessResponse{}}
+
+	config, statuses, version := h.state.getVersion()
+	for i, proj := range config.Projects {
+		project := projectReadinessResponse{Name: proj.Name, Ready: true}
+		if err := h.checkProject(r.Context(), config, version, i, statuses[i]); err != nil {
+			reason := err.Error()
+			project.Ready = false
+			project.Reason = &reason
+			result.Ready = false
+		}
+		result.Projects = append(result.Projects, project)
+	}
+
+	code := http.StatusOK
+	if !result.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, result)
+}
+
+func (h *healthChecker) checkProject(ctx context.Context, config Config, version, projPos int, projStatus *projectStatus) error {
+	status := projStatus.snapshot()
+	if status.GaveUp {
+		return fmt.Errorf("project spent its error budget: %w", status.LastErr)
+	}
+
+	schedule, err := newProjectSchedule(config, projPos)
+	if err != nil {
+		return err
+	}
+	// projects that run once are ready until they give up
+	if schedule != nil {
+		factor := DEFAULT_READINESS_FACTOR
+		if config.ReadinessFactor != nil {
+			factor = *config.ReadinessFactor
+		}
+
+		since := status.LastSuccessAt
+		if since.IsZero() {
+			since = status.StartedAt
+		}
+		interval := schedule.Next(since).Sub(since)
+		if deadline := since.Add(time.Duration(factor) * interval); time.Now().After(deadline) {
+			if status.LastSuccessAt.IsZero() {
+				return fmt.Errorf("no sync cycle finished since %s", since.Format(time.RFC3339))
+			}
+			return fmt.Errorf("last sync cycle finished at %s", since.Format(time.RFC3339))
+		}
+	}
+
+	return h.checkCredentials(ctx, config, version, projPos)
+}
+
+// checkCredentials checks the project GitHub and Jira credentials, reusing
+// the last result while it's fresh and the config was not reloaded.
+func (h *healthChecker) checkCredentials(ctx context.Context, config Config, version, projPos int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	projectCfg := config.Projects[projPos]
+	if check, ok := h.checks[projectCfg.Name]; ok && check.version == version && time.Since(check.checkedAt) < READINESS_CREDENTIALS_TTL {
+		return check.err
+	}
+
+	ctx, cancel := context.WithTimeout(metrics.WithProject(ctx, projectCfg.Name), READINESS_CREDENTIALS_TIMEOUT)
+	defer cancel()
+
+	var errs []error
+	gh, err := h.ghs.get(projectCfg.Name)
+	if err == nil {
+		_, _, err = gh.GetProjectFields(ctx, projectCfg.Github.ProjectID)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("github credentials check failed: %w", err))
+	}
+	jc, err := newProjectJiraClient(h.args, config, projPos)
+	if err == nil {
+		_, _, err = jc.MySelf.Details(ctx, nil)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("jira credentials check failed: %w", err))
+	}
+
+	err = errors.Join(errs...)
+	h.checks[projectCfg.Name] = credentialsCheck{checkedAt: time.Now(), version: version, err: err}
+	return err
+}
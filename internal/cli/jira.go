@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	jira "github.com/ctreminiom/go-atlassian/jira/v3"
+	jiramodels "github.com/ctreminiom/go-atlassian/pkg/infra/models"
+	"github.com/iolave/jira-tickets-from-gh/internal/metrics"
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+)
+
+// JIRA_ISSUE_PROPERTY_KEY is the key of the issue entity property stamped
+// into every jira issue created by this program.
+const JIRA_ISSUE_PROPERTY_KEY = "jira-tickets-from-gh"
+
+// JiraIssueProperty is the value of the issue entity property that links a
+// jira issue to a GitHub project item.
+type JiraIssueProperty struct {
+	GitHubProjectID string `json:"githubProjectId"`
+	GitHubItemID    string `json:"githubItemId"`
+}
+
+// newProjectJiraClient creates a jira client for a sync project using the
+// project credentials.
+func newProjectJiraClient(args Cmd, config Config, projPos int) (*jira.Client, error) {
+	projectCfg := config.Projects[projPos]
+
+	token, email, err := getProjectJiraCreds(args, projectCfg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return newJiraClient(projectCfg.Jira.Subdomain, email, token)
+}
+
+// newJiraClient creates a jira client for the given subdomain using basic
+// auth.
+func newJiraClient(subdomain, email, token string) (*jira.Client, error) {
+	url := fmt.Sprintf("https://%s.atlassian.net", subdomain)
+	client := &http.Client{Transport: metrics.NewTransport(metrics.SERVICE_JIRA, nil)}
+	jc, err := jira.New(client, url)
+	if err != nil {
+		return nil, err
+	}
+	jc.Auth.SetBasicAuth(email, token)
+
+	return jc, nil
+}
+
+// explainJiraNotFound tells a missing jira resource apart from rejected
+// credentials, as jira answers anonymous requests (i.e. user searches) with
+// a 404 instead of a 401.
+func explainJiraNotFound(ctx context.Context, jc *jira.Client, err error) error {
+	if !errors.Is(err, jiramodels.ErrNotFound) {
+		return err
+	}
+	if _, _, meErr := jc.MySelf.Details(ctx, nil); meErr != nil {
+		return fmt.Errorf("jira credentials rejected: %w", meErr)
+	}
+	return err
+}
+
+// JIRA_ITEM_LABEL_PREFIX prefixes the label that marks a jira issue with its
+// GitHub project item id. Unlike entity properties (which are only indexed
+// for apps that declare them), labels can be searched with JQL.
+const JIRA_ITEM_LABEL_PREFIX = "gh-item-"
+
+// JIRA_ITEM_SEARCH_LIMIT is the number of issues labeled with an item that
+// are checked when looking for its jira issue.
+const JIRA_ITEM_SEARCH_LIMIT = 10
+
+func getJiraItemLabel(itemId string) string {
+	return JIRA_ITEM_LABEL_PREFIX + itemId
+}
+
+// createJiraIssue creates a jira issue marked with its GitHub project item,
+// both with the item label and the issue entity property, so the issue can
+// be found from the moment it exists.
+func createJiraIssue(ctx context.Context, jc *jira.Client, p models.Project, itemId string, issue *jiramodels.IssueScheme, customFields *jiramodels.CustomFields) (string, error) {
+	issue.Fields.Labels = append(issue.Fields.Labels, getJiraItemLabel(itemId))
+
+	payload, err := issue.MergeCustomFields(customFields)
+	if errors.Is(err, jiramodels.ErrNoCustomFieldError) {
+		payload, err = toJSONMap(issue)
+	}
+	if err != nil {
+		return "", err
+	}
+	payload["properties"] = []jiramodels.EntityPropertyScheme{{
+		Key:   JIRA_ISSUE_PROPERTY_KEY,
+		Value: JiraIssueProperty{GitHubProjectID: p.ID, GitHubItemID: itemId},
+	}}
+
+	req, err := jc.NewRequest(ctx, http.MethodPost, "rest/api/3/issue", "", payload)
+	if err != nil {
+		return "", err
+	}
+	result := new(jiramodels.IssueResponseScheme)
+	if _, err := jc.Call(req, result); err != nil {
+		return "", err
+	}
+
+	return result.Key, nil
+}
+
+// stampJiraIssue sets the issue entity property and the item label that link
+// the jira issue to the GitHub project item.
+func stampJiraIssue(ctx context.Context, jc *jira.Client, p models.Project, key, itemId string) error {
+	_, err := jc.Issue.Property.Set(ctx, key, JIRA_ISSUE_PROPERTY_KEY, JiraIssueProperty{
+		GitHubProjectID: p.ID,
+		GitHubItemID:    itemId,
+	})
+	recordAudit(p, itemId, key, models.AUDIT_ACTION_JIRA_PROPERTY_SET, fmt.Sprintf(`set property "%s"`, JIRA_ISSUE_PROPERTY_KEY), err)
+	if err != nil {
+		return err
+	}
+
+	return updateJiraItemLabel(ctx, jc, p, key, itemId, "add")
+}
+
+// toJSONMap turns a value into the map its json encoding decodes to.
+func toJSONMap(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]any{}
+	return m, json.Unmarshal(b, &m)
+}
+
+// updateJiraItemLabel adds or removes the item label of a jira issue.
+func updateJiraItemLabel(ctx context.Context, jc *jira.Client, p models.Project, key, itemId, action string) error {
+	label := getJiraItemLabel(itemId)
+	operations := &jiramodels.UpdateOperations{}
+	if err := operations.AddArrayOperation("labels", map[string]string{label: action}); err != nil {
+		return err
+	}
+	_, err := jc.Issue.Update(ctx, key, false, &jiramodels.IssueScheme{}, nil, operations)
+	recordAudit(p, itemId, key, models.AUDIT_ACTION_JIRA_FIELD_UPDATE, fmt.Sprintf(`%s label "%s"`, action, label), err)
+	return err
+}
+
+// findJiraIssueByItem searches the jira project for an issue labeled with the
+// given GitHub project item and whose entity property confirms it, if no
+// issue is found an empty key is returned.
+func findJiraIssueByItem(ctx context.Context, jc *jira.Client, config Config, projPos int, projectId, itemId string) (string, error) {
+	jql := fmt.Sprintf(
+		`project = "%s" AND labels = "%s" ORDER BY created ASC`,
+		config.Projects[projPos].Jira.ProjectKey,
+		getJiraItemLabel(itemId),
+	)
+	result, _, err := jc.Issue.Search.Post(ctx, jql, []string{"summary"}, nil, 0, JIRA_ITEM_SEARCH_LIMIT, "")
+	if err != nil {
+		return "", err
+	}
+
+	for _, issue := range result.Issues {
+		ok, err := isJiraIssueStampedWith(ctx, jc, issue.Key, projectId, itemId)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return issue.Key, nil
+		}
+	}
+
+	return "", nil
+}
+
+// isJiraIssueStampedWith reports whether the issue entity property links the
+// jira issue to the given GitHub project item.
+func isJiraIssueStampedWith(ctx context.Context, jc *jira.Client, key, projectId, itemId string) (bool, error) {
+	property, _, err := jc.Issue.Property.Get(ctx, key, JIRA_ISSUE_PROPERTY_KEY)
+	if errors.Is(err, jiramodels.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var value JiraIssueProperty
+	b, err := json.Marshal(property.Value)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return false, nil
+	}
+
+	return value.GitHubProjectID == projectId && value.GitHubItemID == itemId, nil
+}
+
+func getJiraIssueUrl(config Config, projPos int, key string) string {
+	return fmt.Sprintf("https://%s.atlassian.net/browse/%s", config.Projects[projPos].Jira.Subdomain, key)
+}
+
+// getJiraIssueSummary prepends the configured issue prefix to the title.
+func getJiraIssueSummary(config Config, projPos int, title string) string {
+	if prefix := config.Projects[projPos].Jira.IssuePrefix; prefix != nil && *prefix != "" {
+		return fmt.Sprintf("%s %s", *prefix, title)
+	}
+	return title
+}
+
+// getProjectWorkflowScheme retrieves the workflow scheme of a jira project,
+// which requires the jira administer permission.
+func getProjectWorkflowScheme(ctx context.Context, jc *jira.Client, projectId string) (*jiramodels.WorkflowSchemeScheme, error) {
+	id, err := strconv.Atoi(projectId)
+	if err != nil {
+		return nil, err
+	}
+	associations, _, err := jc.Workflow.Scheme.Associations(ctx, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(associations.Values) == 0 || associations.Values[0].WorkflowScheme == nil {
+		return nil, errors.New("project without workflow scheme")
+	}
+	return associations.Values[0].WorkflowScheme, nil
+}
+
+// getIssueTypeWorkflow returns the name of the workflow an issue type uses
+// within a workflow scheme, falling back to the scheme default workflow.
+func getIssueTypeWorkflow(ctx context.Context, jc *jira.Client, scheme *jiramodels.WorkflowSchemeScheme, issueTypeId string) string {
+	mapping, _, err := jc.Workflow.Scheme.IssueType.Get(ctx, scheme.ID, issueTypeId, false)
+	if err != nil || mapping.Workflow == "" {
+		return scheme.DefaultWorkflow
+	}
+	return mapping.Workflow
+}
+
+// getWorkflowTransitions retrieves the transitions of a jira workflow.
+func getWorkflowTransitions(ctx context.Context, jc *jira.Client, name string) ([]*jiramodels.WorkflowTransitionScheme, error) {
+	opts := &jiramodels.WorkflowSearchOptions{WorkflowName: []string{name}, Expand: []string{"transitions"}}
+	page, _, err := jc.Workflow.Gets(ctx, opts, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Values) == 0 {
+		return nil, errors.New("workflow not found")
+	}
+	return page.Values[0].Transitions, nil
+}
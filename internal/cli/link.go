@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	jira "github.com/ctreminiom/go-atlassian/jira/v3"
+	"github.com/iolave/jira-tickets-from-gh/internal/github"
+	"github.com/iolave/jira-tickets-from-gh/internal/metrics"
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+type LinkCmd struct {
+	Config  string `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project string `arg:"required,--project" help:"sync project name" placeholder:"<NAME>"`
+	Item    string `arg:"required,--item" help:"GitHub project item id" placeholder:"<ID>"`
+	Jira    string `arg:"required,--jira" help:"existing Jira issue key" placeholder:"<KEY>"`
+	Force   bool   `arg:"--force" help:"link the item even if it or the Jira issue are already linked to something else"`
+}
+
+type UnlinkCmd struct {
+	Config  string `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project string `arg:"required,--project" help:"sync project name" placeholder:"<NAME>"`
+	Item    string `arg:"required,--item" help:"GitHub project item id" placeholder:"<ID>"`
+}
+
+// LinkAction links a GitHub project item to an existing jira issue, so the
+// sync doesn't create a new one for it.
+func LinkAction(args Cmd) {
+	if args.Link == nil {
+		exitOnInvalidCall("link")
+	}
+	cmd := args.Link
+
+	env := newLinkEnv(args, cmd.Config, cmd.Project)
+	defer env.m.Close()
+
+	if !cmd.Force {
+		if err := checkLinkable(*env.p, env.config, env.projPos, cmd.Item, cmd.Jira); err != nil {
+			exitFromErr(fmt.Errorf("%w, use --force to link it anyway", err))
+		}
+	}
+
+	is, err := linkItem(env.ctx, env.config, env.projPos, env.jc, env.gh, *env.p, cmd.Item, cmd.Jira)
+	if err != nil {
+		env.log.WithFields(logrus.Fields{"err": err, "project": cmd.Project, "itemId": cmd.Item, "jiraKey": cmd.Jira}).Errorln("linking item failed")
+		exitFromErr(err)
+	}
+
+	fmt.Printf("linked item %s to %s\n", is.GitHubID, *is.JiraURL)
+}
+
+// UnlinkAction removes the link between a GitHub project item and its jira
+// issue, see unlinkItem.
+func UnlinkAction(args Cmd) {
+	if args.Unlink == nil {
+		exitOnInvalidCall("unlink")
+	}
+	cmd := args.Unlink
+
+	env := newLinkEnv(args, cmd.Config, cmd.Project)
+	defer env.m.Close()
+
+	if err := unlinkItem(env.ctx, env.jc, env.gh, *env.p, cmd.Item); err != nil {
+		env.log.WithFields(logrus.Fields{"err": err, "project": cmd.Project, "itemId": cmd.Item}).Errorln("unlinking item failed")
+		exitFromErr(err)
+	}
+
+	fmt.Printf("unlinked item %s\n", cmd.Item)
+}
+
+// linkEnv holds what the commands that link items to jira issues work with.
+type linkEnv struct {
+	ctx     context.Context
+	config  Config
+	projPos int
+	m       *models.Models
+	p       *models.Project
+	jc      *jira.Client
+	gh      *github.GitHubClient
+	log     *logrus.Logger
+}
+
+// newLinkEnv reads the config and creates the clients and project of the
+// given sync project, exiting on failure.
+func newLinkEnv(args Cmd, configPath, project string) linkEnv {
+	level := logrus.InfoLevel
+	if args.Debug != nil && *args.Debug == true {
+		level = logrus.DebugLevel
+	}
+	log := newLogger(level)
+
+	config, err := readConfig(configPath, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	projPos := getProjectPosByName(config, project)
+	if projPos == -1 {
+		exitFromErr(fmt.Errorf(`sync project "%s" not found in config`, project))
+	}
+
+	gh, err := newProjectGithubClient(args, project)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	jc, err := newProjectJiraClient(args, config, projPos)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	m, err := initializeModels(args, configPath, config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	ctx := metrics.WithProject(context.Background(), project)
+	p, err := upsertProjectFields(ctx, config, projPos, m, gh, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	return linkEnv{ctx: ctx, config: config, projPos: projPos, m: m, p: p, jc: jc, gh: gh, log: log}
+}
+
+// checkLinkable fails if the item is already linked to another jira issue,
+// or if the jira issue is already linked to another stored item.
+func checkLinkable(p models.Project, config Config, projPos int, itemId, key string) error {
+	url := getJiraIssueUrl(config, projPos, strings.ToUpper(key))
+
+	is, err := p.GetIssue(itemId)
+	if err != nil {
+		return err
+	}
+	if is != nil && is.JiraURL != nil && *is.JiraURL != url {
+		return fmt.Errorf(`item "%s" is already linked to %s`, itemId, *is.JiraURL)
+	}
+
+	linked, err := p.GetIssuesWithUrl()
+	if err != nil {
+		return err
+	}
+	for _, other := range linked {
+		if other.GitHubID != itemId && *other.JiraURL == url {
+			return fmt.Errorf(`jira issue "%s" is already linked to item "%s"`, strings.ToUpper(key), other.GitHubID)
+		}
+	}
+
+	return nil
+}
+
+// writeJiraUrlToGithub writes the jira issue url into the item "Jira URL"
+// field.
+func writeJiraUrlToGithub(ctx context.Context, gh *github.GitHubClient, p models.Project, itemId, key, url string) error {
+	_, _, err := gh.UpdateProjectItemField(ctx, p.ID, itemId, p.Fields.JiraURL, github.PROJECT_FIELD_TEXT, url)
+	recordAudit(p, itemId, key, models.AUDIT_ACTION_GITHUB_FIELD_WRITE, fmt.Sprintf(`write "%s" field = %s`, models.FIELD_NAME_JIRA_URL, url), err)
+	return err
+}
+
+// findRemoteIssue retrieves a GitHub project item by its id, if the item is
+// not part of the project (or it is archived) *models.RemoteIssue will be nil.
+func findRemoteIssue(ctx context.Context, gh *github.GitHubClient, projectId, itemId string) (*models.RemoteIssue, error) {
+	remoteIssues, _, err := fetchRemoteIssues(ctx, gh, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ri := range remoteIssues {
+		if ri.ID == itemId {
+			return &ri, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// linkItem links a GitHub project item to an existing jira issue: the jira
+// issue is stamped with the item, its url is written into the item "Jira URL"
+// field and the item is stored with it.
+func linkItem(
+	ctx context.Context,
+	config Config,
+	projPos int,
+	jc *jira.Client,
+	gh *github.GitHubClient,
+	p models.Project,
+	itemId string,
+	key string,
+) (*models.Issue, error) {
+	jiraIssue, _, err := jc.Issue.Get(ctx, strings.ToUpper(key), []string{"summary"}, nil)
+	if err != nil {
+		return nil, fmt.Errorf(`jira issue "%s" could not be retrieved: %w`, key, err)
+	}
+	key = jiraIssue.Key
+
+	ri, err := findRemoteIssue(ctx, gh, p.ID, itemId)
+	if err != nil {
+		return nil, err
+	}
+	if ri == nil {
+		return nil, fmt.Errorf(`item "%s" not found in github project "%s"`, itemId, p.ID)
+	}
+	// the sync only transitions jira issues from the statuses it knows
+	if ri.Status == nil || !models.IssueStatus(ri.Status.Name).IsKnown() {
+		return nil, fmt.Errorf(`item "%s" status should be one of [%s, %s, %s] to be linked`, itemId, models.STATUS_TODO, models.STATUS_WIP, models.STATUS_DONE)
+	}
+
+	url := getJiraIssueUrl(config, projPos, key)
+	if err := stampJiraIssue(ctx, jc, p, key, itemId); err != nil {
+		return nil, err
+	}
+	if err := writeJiraUrlToGithub(ctx, gh, p, itemId, key, url); err != nil {
+		return nil, err
+	}
+
+	is := ri.ToIssue(p.ID)
+	is.JiraURL = &url
+
+	return p.UpsertIssue(
+		is.GitHubID,
+		is.Title,
+		is.Status,
+		is.JiraURL,
+		is.JiraIssueType,
+		is.Repository,
+		is.Estimate,
+		&is.Assignees,
+	)
+}
+
+// unlinkItem removes the link between a GitHub project item and its jira
+// issue, the jira issue itself is left untouched. Keep in mind the next sync
+// creates a new jira issue for the item.
+func unlinkItem(
+	ctx context.Context,
+	jc *jira.Client,
+	gh *github.GitHubClient,
+	p models.Project,
+	itemId string,
+) error {
+	is, err := p.GetIssue(itemId)
+	if err != nil {
+		return err
+	}
+	if is == nil {
+		return fmt.Errorf(`item "%s" is not stored for github project "%s"`, itemId, p.ID)
+	}
+
+	key := getJiraIssueKey(is.JiraURL)
+	_, _, err = gh.ClearProjectItemField(ctx, p.ID, itemId, p.Fields.JiraURL)
+	recordAudit(p, itemId, key, models.AUDIT_ACTION_GITHUB_FIELD_CLEAR, fmt.Sprintf(`clear "%s" field`, models.FIELD_NAME_JIRA_URL), err)
+	if err != nil {
+		return err
+	}
+	if err := p.ClearIssueUrl(itemId); err != nil {
+		return err
+	}
+
+	if key != "" {
+		_, err := jc.Issue.Property.Delete(ctx, key, JIRA_ISSUE_PROPERTY_KEY)
+		recordAudit(p, itemId, key, models.AUDIT_ACTION_JIRA_PROPERTY_DELETE, fmt.Sprintf(`delete property "%s"`, JIRA_ISSUE_PROPERTY_KEY), err)
+		if err != nil {
+			return fmt.Errorf(`unlinked item but jira issue "%s" property could not be removed: %w`, key, err)
+		}
+		if err := updateJiraItemLabel(ctx, jc, p, key, itemId, "remove"); err != nil {
+			return fmt.Errorf(`unlinked item but jira issue "%s" label could not be removed: %w`, key, err)
+		}
+	}
+
+	return nil
+}
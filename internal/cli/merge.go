@@ -0,0 +1,304 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CONFIG_FRAGMENT_EXTENSIONS are the extensions of the files included from a
+// directory.
+var CONFIG_FRAGMENT_EXTENSIONS = []string{".yml", ".yaml"}
+
+// CONFIG_FRAGMENT_PROPERTIES are the properties an included config file can
+// hold.
+var CONFIG_FRAGMENT_PROPERTIES = []string{"include", "sync"}
+
+// configFile is a config file read while resolving the includes.
+type configFile struct {
+	path    string
+	content []byte
+	root    *yaml.Node // top level mapping
+}
+
+// readConfigFiles reads the config file and the files it includes, in the
+// order their projects are merged. The files read before an error is found
+// are returned along with it.
+func readConfigFiles(path string) ([]configFile, error) {
+	files := []configFile{}
+	err := readConfigFile(path, true, &files)
+	return files, err
+}
+
+// readConfigFile reads a config file, interpolates the env variables of its
+// values and reads the files it includes, relative paths are resolved from the file
+// directory.
+func readConfigFile(path string, main bool, files *[]configFile) error {
+	wrap := func(err error) error {
+		if main {
+			return err
+		}
+		return fmt.Errorf(`included config "%s": %w`, path, err)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return wrap(err)
+	}
+	if slices.ContainsFunc(*files, func(f configFile) bool { return f.path == abs }) {
+		return fmt.Errorf(`config "%s" is included more than once`, path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	*files = append(*files, configFile{path: abs, content: content})
+	pos := len(*files) - 1
+
+	root, err := parseConfigMapping(content)
+	if err != nil {
+		return wrap(err)
+	}
+	if err := interpolateConfigEnv(root); err != nil {
+		return wrap(err)
+	}
+	(*files)[pos].root = root
+
+	if !main {
+		for i := 0; i < len(root.Content); i += 2 {
+			if key := root.Content[i].Value; !slices.Contains(CONFIG_FRAGMENT_PROPERTIES, key) {
+				return wrap(fmt.Errorf(`"%s" property is not allowed, included configs can only hold [%s]`, key, strings.Join(CONFIG_FRAGMENT_PROPERTIES, ", ")))
+			}
+		}
+	}
+
+	includes, err := getConfigIncludes(root)
+	if err != nil {
+		return wrap(err)
+	}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		paths, err := listConfigInclude(include)
+		if err != nil {
+			return wrap(err)
+		}
+		for _, p := range paths {
+			if err := readConfigFile(p, false, files); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseConfigMapping parses a config file content, which must be a mapping.
+// An empty content is parsed as an empty mapping.
+func parseConfigMapping(b []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("config should be a mapping of properties")
+	}
+	return root, nil
+}
+
+// getConfigIncludes returns the paths of the "include" property, either a
+// path or a list of them.
+func getConfigIncludes(root *yaml.Node) ([]string, error) {
+	node := getMappingValue(root, "include")
+	if node == nil {
+		return nil, nil
+	}
+
+	includes := []string{}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		includes = append(includes, node.Value)
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf(`"include[%d]" property should be a path`, i)
+			}
+			includes = append(includes, item.Value)
+		}
+	default:
+		return nil, errors.New(`"include" property should be a path or a list of paths`)
+	}
+
+	for i, include := range includes {
+		if include == "" {
+			return nil, fmt.Errorf(`"include[%d]" property is empty`, i)
+		}
+	}
+	return includes, nil
+}
+
+// listConfigInclude returns the included file, or the yaml files within the
+// included directory sorted by name.
+func listConfigInclude(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains(CONFIG_FRAGMENT_EXTENSIONS, filepath.Ext(entry.Name())) {
+			continue
+		}
+		paths = append(paths, filepath.Join(path, entry.Name()))
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// mergeConfigFiles appends the projects of the included files to the ones
+// of the config file and merges the "defaults" property into every project.
+func mergeConfigFiles(files []configFile) (*yaml.Node, error) {
+	root := files[0].root
+	deleteMappingValue(root, "include")
+
+	projects := getMappingValue(root, "sync")
+	if projects == nil || isNullNode(projects) {
+		projects = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		deleteMappingValue(root, "sync")
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "sync"}, projects)
+	}
+	if projects.Kind != yaml.SequenceNode {
+		return nil, errors.New(`"sync" property should be a list`)
+	}
+
+	for _, file := range files[1:] {
+		fragment := getMappingValue(file.root, "sync")
+		if fragment == nil || isNullNode(fragment) {
+			continue
+		}
+		if fragment.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf(`"sync" property of included config "%s" should be a list`, file.path)
+		}
+		projects.Content = append(projects.Content, fragment.Content...)
+	}
+
+	defaults := getMappingValue(root, "defaults")
+	deleteMappingValue(root, "defaults")
+	if defaults == nil || isNullNode(defaults) {
+		return root, nil
+	}
+	defaults = resolveAlias(defaults)
+	if defaults.Kind != yaml.MappingNode {
+		return nil, errors.New(`"defaults" property should be a mapping`)
+	}
+
+	for i, project := range projects.Content {
+		project = resolveAlias(project)
+		if project.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf(`"sync[%d]" property should be a mapping`, i)
+		}
+		projects.Content[i] = mergeConfigNodes(defaults, project)
+	}
+
+	return root, nil
+}
+
+// mergeConfigNodes returns the base node overridden by the given one.
+// Mappings are merged key by key, any other value (lists included) is
+// replaced as a whole. The given nodes are not modified.
+func mergeConfigNodes(base, override *yaml.Node) *yaml.Node {
+	base, override = resolveAlias(base), resolveAlias(override)
+	if base.Kind != yaml.MappingNode || override.Kind != yaml.MappingNode {
+		return override
+	}
+
+	merged := *override
+	merged.Content = slices.Clone(base.Content)
+	for i := 0; i < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		pos := getMappingKeyPos(&merged, key.Value)
+		if pos == -1 {
+			merged.Content = append(merged.Content, key, value)
+			continue
+		}
+		merged.Content[pos+1] = mergeConfigNodes(merged.Content[pos+1], value)
+	}
+
+	return &merged
+}
+
+// getConfigHash returns the hash of the config file and the files it
+// includes, a file that can't be parsed still changes the hash.
+func getConfigHash(configPath string) ([sha256.Size]byte, error) {
+	files, err := readConfigFiles(configPath)
+	if len(files) == 0 {
+		return [sha256.Size]byte{}, err
+	}
+
+	h := sha256.New()
+	for _, file := range files {
+		fmt.Fprintf(h, "%s\x00%d\x00", file.path, len(file.content))
+		h.Write(file.content)
+	}
+
+	var hash [sha256.Size]byte
+	copy(hash[:], h.Sum(nil))
+	return hash, nil
+}
+
+func getMappingKeyPos(mapping *yaml.Node, key string) int {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func getMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	pos := getMappingKeyPos(mapping, key)
+	if pos == -1 {
+		return nil
+	}
+	return resolveAlias(mapping.Content[pos+1])
+}
+
+func deleteMappingValue(mapping *yaml.Node, key string) {
+	if pos := getMappingKeyPos(mapping, key); pos != -1 {
+		mapping.Content = slices.Delete(mapping.Content, pos, pos+2)
+	}
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func isNullNode(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeConfigFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string // by path relative to the config directory
+		want  string
+		err   string
+	}{
+		{
+			name: "defaults mappings are merged key by key",
+			files: map[string]string{"config.yml": `
+defaults:
+  jira:
+    subdomain: myorg
+    issues:
+      estimateField: points
+sync:
+  - name: a
+    jira:
+      projectKey: A
+      issues:
+        defaultType: Task
+`},
+			want: `
+sync:
+  - name: a
+    jira:
+      subdomain: myorg
+      projectKey: A
+      issues:
+        estimateField: points
+        defaultType: Task
+`,
+		},
+		{
+			name: "project values override the defaults",
+			files: map[string]string{"config.yml": `
+defaults:
+  jira:
+    subdomain: myorg
+sync:
+  - name: a
+    jira:
+      subdomain: other
+`},
+			want: `
+sync:
+  - name: a
+    jira:
+      subdomain: other
+`,
+		},
+		{
+			name: "defaults lists are replaced",
+			files: map[string]string{"config.yml": `
+defaults:
+  assignees:
+    - ghUser: a
+    - ghUser: b
+sync:
+  - name: a
+    assignees:
+      - ghUser: c
+  - name: b
+`},
+			want: `
+sync:
+  - name: a
+    assignees:
+      - ghUser: c
+  - name: b
+    assignees:
+      - ghUser: a
+      - ghUser: b
+`,
+		},
+		{
+			name: "included projects are appended and inherit the defaults",
+			files: map[string]string{
+				"config.yml": `
+include:
+  - teams
+  - extra.yml
+defaults:
+  jira:
+    subdomain: myorg
+sync:
+  - name: main
+`,
+				"teams/b.yml":  "sync:\n  - name: b\n",
+				"teams/a.yaml": "sync:\n  - name: a\n",
+				"teams/c.txt":  "sync:\n  - name: c\n",
+				"extra.yml":    "sync:\n  - name: extra\n",
+			},
+			want: `
+sync:
+  - name: main
+    jira:
+      subdomain: myorg
+  - name: a
+    jira:
+      subdomain: myorg
+  - name: b
+    jira:
+      subdomain: myorg
+  - name: extra
+    jira:
+      subdomain: myorg
+`,
+		},
+		{
+			name: "included files resolve their includes from their directory",
+			files: map[string]string{
+				"config.yml":         "include: teams/a.yml\n",
+				"teams/a.yml":        "include: nested/b.yml\nsync:\n  - name: a\n",
+				"teams/nested/b.yml": "sync:\n  - name: b\n",
+			},
+			want: `
+sync:
+  - name: a
+  - name: b
+`,
+		},
+		{
+			name: "include cycles are refused",
+			files: map[string]string{
+				"config.yml": "include: a.yml\n",
+				"a.yml":      "include: b.yml\n",
+				"b.yml":      "include: a.yml\n",
+			},
+			err: "is included more than once",
+		},
+		{
+			name: "including the config itself is refused",
+			files: map[string]string{
+				"config.yml": "include: config.yml\n",
+			},
+			err: "is included more than once",
+		},
+		{
+			name: "included files can't hold other properties",
+			files: map[string]string{
+				"config.yml": "include: a.yml\n",
+				"a.yml":      "sleepTime: 10\n",
+			},
+			err: `"sleepTime" property is not allowed`,
+		},
+		{
+			name: "defaults must be a mapping",
+			files: map[string]string{
+				"config.yml": "defaults: [a]\nsync:\n  - name: a\n",
+			},
+			err: `"defaults" property should be a mapping`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for path, content := range tt.files {
+				path = filepath.Join(dir, path)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			files, err := readConfigFiles(filepath.Join(dir, "config.yml"))
+			var root *yaml.Node
+			if err == nil {
+				root, err = mergeConfigFiles(files)
+			}
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got, want any
+			if err := root.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				b, _ := yaml.Marshal(got)
+				t.Errorf("got:\n%s\nwant:%s", b, tt.want)
+			}
+		})
+	}
+}
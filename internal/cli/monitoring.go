@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+const DEFAULT_METRICS_ADDRESS = ":9090"
+
+// startMonitoringServer serves the prometheus metrics at "/metrics" (when
+// "enableMetrics" is set) and the health endpoints at "/healthz" and
+// "/readyz" (when "enableHealth" is set) until ctx is cancelled.
+func startMonitoringServer(ctx context.Context, args Cmd, state *syncState, ghs *githubClients, log *logrus.Logger) {
+	config, _ := state.get()
+	address := DEFAULT_METRICS_ADDRESS
+	if config.MetricsAddress != nil {
+		address = *config.MetricsAddress
+	}
+
+	mux := http.NewServeMux()
+	if config.EnableMetrics != nil && *config.EnableMetrics {
+		mux.Handle("GET /metrics", metrics.Handler())
+	}
+	if config.EnableHealth != nil && *config.EnableHealth {
+		health := newHealthChecker(args, state, ghs)
+		mux.HandleFunc("GET /healthz", health.liveness)
+		mux.HandleFunc("GET /readyz", health.readiness)
+	}
+	server := &http.Server{Addr: address, Handler: mux}
+
+	go func() {
+		log.WithFields(logrus.Fields{"address": address}).Infoln("serving monitoring endpoints")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithFields(logrus.Fields{"err": err, "address": address}).Errorln("serving monitoring endpoints failed")
+		}
+	}()
+
+	context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), API_SHUTDOWN_GRACE_PERIOD)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	})
+}
@@ -0,0 +1,380 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	jira "github.com/ctreminiom/go-atlassian/jira/v3"
+	"github.com/iolave/jira-tickets-from-gh/internal/github"
+	"github.com/iolave/jira-tickets-from-gh/internal/helpers"
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// EXIT_CODE_CHANGES_PENDING is the exit code of a dry-run execution whose
+// plan contains at least one change.
+const EXIT_CODE_CHANGES_PENDING = 2
+
+const (
+	PLAN_FORMAT_TEXT string = "text"
+	PLAN_FORMAT_JSON string = "json"
+)
+
+type PlanActionKind string
+
+const (
+	PLAN_ACTION_JIRA_CREATE        PlanActionKind = "jira_create"
+	PLAN_ACTION_JIRA_LINK          PlanActionKind = "jira_link"
+	PLAN_ACTION_JIRA_TRANSITION    PlanActionKind = "jira_transition"
+	PLAN_ACTION_JIRA_FIELD_UPDATE  PlanActionKind = "jira_field_update"
+	PLAN_ACTION_JIRA_COMMENT       PlanActionKind = "jira_comment"
+	PLAN_ACTION_GITHUB_FIELD_WRITE PlanActionKind = "github_field_write"
+)
+
+// PlanAction is a change that a sync cycle would do.
+type PlanAction struct {
+	Kind        PlanActionKind `json:"kind"`
+	ItemID      string         `json:"itemId"`
+	Title       string         `json:"title,omitempty"`
+	JiraKey     string         `json:"jiraKey,omitempty"`
+	Description string         `json:"description"`
+}
+
+// ProjectPlan holds every change that the next sync cycle of a project would do.
+type ProjectPlan struct {
+	Project string       `json:"project"`
+	Actions []PlanAction `json:"actions"`
+}
+
+// planProject runs the discovery and diff pipeline of a project without
+// changing anything neither in GitHub, Jira nor the local storage, and
+// returns the changes that the next sync cycle would do.
+func planProject(ctx context.Context, args Cmd, config Config, projPos int, m *models.Models, gh *github.GitHubClient, log *logrus.Logger) (ProjectPlan, error) {
+	projectCfg := config.Projects[projPos]
+	plan := ProjectPlan{Project: projectCfg.Name, Actions: []PlanAction{}}
+
+	jc, err := newProjectJiraClient(args, config, projPos)
+	if err != nil {
+		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("failed creating jira client")
+		return plan, err
+	}
+
+	if _, err := getProjectFieldsIds(ctx, config, projPos, gh, log); err != nil {
+		return plan, err
+	}
+
+	log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("querying gh remote issues")
+	allRemoteIssues, _, err := fetchRemoteIssues(ctx, gh, projectCfg.Github.ProjectID)
+	if err != nil {
+		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("querying gh remote issues failed")
+		return plan, err
+	}
+	remoteIssues := filterSyncableIssues(allRemoteIssues)
+
+	p, err := m.Projects.Get(projectCfg.Github.ProjectID)
+	if err != nil {
+		return plan, err
+	}
+
+	// the project was never synced, every item would be treated as new
+	if p == nil {
+		for _, ri := range remoteIssues {
+			is := ri.ToIssue(projectCfg.Github.ProjectID)
+			if key := getJiraIssueKey(is.JiraURL); key != "" {
+				plan.Actions = append(plan.Actions, planTransitions(config, projPos, nil, key, *is, models.STATUS_TODO)...)
+				continue
+			}
+			actions, err := planCreate(ctx, config, projPos, jc, *is)
+			if err != nil {
+				return plan, err
+			}
+			plan.Actions = append(plan.Actions, actions...)
+		}
+		return plan, nil
+	}
+
+	ops, err := p.GetPendingOperations()
+	if err != nil {
+		return plan, err
+	}
+	resumed := []string{}
+	for _, op := range ops {
+		if op.Kind != models.OPERATION_KIND_CREATE_ISSUE {
+			continue
+		}
+		is, err := op.Issue()
+		if err != nil {
+			return plan, err
+		}
+		actions, err := planResumeCreate(ctx, config, projPos, jc, *op, *is)
+		if err != nil {
+			return plan, err
+		}
+		plan.Actions = append(plan.Actions, actions...)
+		resumed = append(resumed, op.GitHubID)
+	}
+
+	localIssues, err := p.GetAllIssues()
+	if err != nil {
+		return plan, err
+	}
+	if len(localIssues) == 0 {
+		for _, ri := range remoteIssues {
+			is := ri.ToIssue(p.ID)
+			if slices.Contains(resumed, is.GitHubID) {
+				continue
+			}
+			if key := getJiraIssueKey(is.JiraURL); key != "" {
+				plan.Actions = append(plan.Actions, planTransitions(config, projPos, nil, key, *is, models.STATUS_TODO)...)
+				continue
+			}
+			actions, err := planCreate(ctx, config, projPos, jc, *is)
+			if err != nil {
+				return plan, err
+			}
+			plan.Actions = append(plan.Actions, actions...)
+		}
+		return plan, nil
+	}
+
+	riWithoutUrl := helpers.FilterSlice(remoteIssues, func(ri models.RemoteIssue) bool {
+		return ri.JiraUrl.Text == nil && !slices.Contains(resumed, ri.ID)
+	})
+	for _, ri := range riWithoutUrl {
+		actions, err := planCreate(ctx, config, projPos, jc, *ri.ToIssue(p.ID))
+		if err != nil {
+			return plan, err
+		}
+		plan.Actions = append(plan.Actions, actions...)
+	}
+
+	// items with a jira url that are not stored locally are adopted, so
+	// they don't result in any remote change
+	riWithUrl := helpers.FilterSlice(remoteIssues, func(ri models.RemoteIssue) bool {
+		return ri.JiraUrl.Text != nil
+	})
+	diffs, err := p.GetIssuesDiff(riWithUrl)
+	if err != nil {
+		return plan, err
+	}
+	for _, diff := range diffs {
+		key := getJiraIssueKey(diff.Issue.JiraURL)
+		if key == "" {
+			continue
+		}
+		plan.Actions = append(plan.Actions, planTransitions(config, projPos, nil, key, *diff.Issue, *diff.PrevStatus)...)
+	}
+
+	// removed items are only reconciled by the sync loops and "--prune"
+	prune, err := isPruningRemovedIssues(args, config, projPos)
+	if err != nil || !prune {
+		return plan, err
+	}
+	ids := []string{}
+	for _, ri := range allRemoteIssues {
+		ids = append(ids, ri.ID)
+	}
+	removedIssues, err := p.GetIssuesNotIn(ids)
+	if err != nil {
+		return plan, err
+	}
+	for _, is := range removedIssues {
+		key := getJiraIssueKey(is.JiraURL)
+		if key == "" {
+			continue
+		}
+		if action := planRemovedAction(config, projPos, key, *is); action != nil {
+			plan.Actions = append(plan.Actions, *action)
+		}
+	}
+
+	return plan, nil
+}
+
+// planCreate returns the changes that creating a jira issue from the given
+// item would do.
+func planCreate(ctx context.Context, config Config, projPos int, jc *jira.Client, is models.Issue) ([]PlanAction, error) {
+	if is.Status == nil {
+		return nil, nil
+	}
+
+	actions := []PlanAction{}
+	key, err := findJiraIssueByItem(ctx, jc, config, projPos, is.GitHubProjectID, is.GitHubID)
+	if err != nil {
+		return nil, err
+	}
+
+	if key != "" {
+		actions = append(actions, PlanAction{
+			Kind:        PLAN_ACTION_JIRA_LINK,
+			ItemID:      is.GitHubID,
+			Title:       is.Title,
+			JiraKey:     key,
+			Description: fmt.Sprintf(`link existing jira issue %s labeled and stamped with the item`, key),
+		})
+	} else {
+		issueType := ""
+		if is.JiraIssueType != nil {
+			issueType = *is.JiraIssueType
+		}
+		details := []string{fmt.Sprintf("type %s", issueType)}
+		if config.Projects[projPos].Jira.EstimateField != nil && is.Estimate != nil {
+			details = append(details, fmt.Sprintf("estimate %d", *is.Estimate))
+		}
+		if len(is.Assignees) > 0 {
+			details = append(details, fmt.Sprintf("assignee %s", is.Assignees[0]))
+		}
+		actions = append(actions, PlanAction{
+			Kind:   PLAN_ACTION_JIRA_CREATE,
+			ItemID: is.GitHubID,
+			Title:  is.Title,
+			Description: fmt.Sprintf(`create jira issue "%s" in project %s (%s)`,
+				getJiraIssueSummary(config, projPos, is.Title),
+				config.Projects[projPos].Jira.ProjectKey,
+				strings.Join(details, ", "),
+			),
+		})
+		key = "<new>"
+	}
+
+	actions = append(actions, PlanAction{
+		Kind:        PLAN_ACTION_GITHUB_FIELD_WRITE,
+		ItemID:      is.GitHubID,
+		Title:       is.Title,
+		JiraKey:     key,
+		Description: fmt.Sprintf(`write jira url of %s into the "%s" field`, key, models.FIELD_NAME_JIRA_URL),
+	})
+
+	return append(actions, planTransitions(config, projPos, nil, key, is, models.STATUS_TODO)...), nil
+}
+
+// planResumeCreate returns the changes that resuming a pending issue
+// creation would do.
+func planResumeCreate(ctx context.Context, config Config, projPos int, jc *jira.Client, op models.Operation, is models.Issue) ([]PlanAction, error) {
+	if !op.HasCompleted(OPERATION_STEP_JIRA_CREATED) || op.JiraKey == nil {
+		return planCreate(ctx, config, projPos, jc, is)
+	}
+
+	actions := []PlanAction{}
+	if !op.HasCompleted(OPERATION_STEP_GITHUB_WRITTEN) {
+		actions = append(actions, PlanAction{
+			Kind:        PLAN_ACTION_GITHUB_FIELD_WRITE,
+			ItemID:      is.GitHubID,
+			Title:       is.Title,
+			JiraKey:     *op.JiraKey,
+			Description: fmt.Sprintf(`write jira url of %s into the "%s" field (resumed operation)`, *op.JiraKey, models.FIELD_NAME_JIRA_URL),
+		})
+	}
+	if !op.HasCompleted(OPERATION_STEP_JIRA_TRANSITIONED) {
+		actions = append(actions, planTransitions(config, projPos, &op, *op.JiraKey, is, models.STATUS_TODO)...)
+	}
+
+	return actions, nil
+}
+
+// planTransitions returns the transitions needed to get a jira issue from
+// the given status to the item status, leaving out the ones the given
+// operation, if any, already completed.
+func planTransitions(config Config, projPos int, op *models.Operation, key string, is models.Issue, from models.IssueStatus) []PlanAction {
+	actions := []PlanAction{}
+	if is.Status == nil {
+		return actions
+	}
+
+	issueType := getIssueTypeConfig(config, projPos, is.JiraIssueType)
+	if issueType == nil {
+		return actions
+	}
+
+	toWip := getPendingTransitions(op, OPERATION_STEP_JIRA_TRANSITIONED_TO_WIP, issueType.TransitionsToWIP)
+	toDone := getPendingTransitions(op, OPERATION_STEP_JIRA_TRANSITIONED_TO_DONE, issueType.TransitionsToDone)
+
+	if from == models.STATUS_TODO && (*is.Status == models.STATUS_WIP || *is.Status == models.STATUS_DONE) && len(toWip) > 0 {
+		actions = append(actions, PlanAction{
+			Kind:        PLAN_ACTION_JIRA_TRANSITION,
+			ItemID:      is.GitHubID,
+			Title:       is.Title,
+			JiraKey:     key,
+			Description: fmt.Sprintf(`transition %s to "%s" using transitions %v`, key, models.STATUS_WIP, toWip),
+		})
+	}
+
+	if from != models.STATUS_DONE && *is.Status == models.STATUS_DONE && len(toDone) > 0 {
+		actions = append(actions, PlanAction{
+			Kind:        PLAN_ACTION_JIRA_TRANSITION,
+			ItemID:      is.GitHubID,
+			Title:       is.Title,
+			JiraKey:     key,
+			Description: fmt.Sprintf(`transition %s to "%s" using transitions %v`, key, models.STATUS_DONE, toDone),
+		})
+	}
+
+	return actions
+}
+
+// planRemovedAction returns the change that the "onRemoved" action would do
+// on the jira issue of a removed item, if no action is configured
+// *PlanAction will be nil.
+func planRemovedAction(config Config, projPos int, key string, is models.Issue) *PlanAction {
+	onRemoved := config.Projects[projPos].Jira.OnRemoved
+	action := PlanAction{ItemID: is.GitHubID, Title: is.Title, JiraKey: key}
+
+	switch onRemoved.Action {
+	case REMOVED_ACTION_TRANSITION:
+		action.Kind = PLAN_ACTION_JIRA_TRANSITION
+		action.Description = fmt.Sprintf(`transition %s using transitions %v as the item was removed`, key, onRemoved.Transitions)
+	case REMOVED_ACTION_LABEL:
+		action.Kind = PLAN_ACTION_JIRA_FIELD_UPDATE
+		action.Description = fmt.Sprintf(`add label "%s" to %s as the item was removed`, onRemoved.Label, key)
+	case REMOVED_ACTION_COMMENT:
+		action.Kind = PLAN_ACTION_JIRA_COMMENT
+		action.Description = fmt.Sprintf(`comment on %s as the item was removed`, key)
+	default:
+		return nil
+	}
+
+	return &action
+}
+
+// writePlans writes the projects plans in the given format.
+func writePlans(w io.Writer, plans []ProjectPlan, format string) error {
+	if format == PLAN_FORMAT_JSON {
+		b, err := json.Marshal(plans)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	total := 0
+	for _, plan := range plans {
+		fmt.Fprintf(w, "project %s: %d change(s)\n", plan.Project, len(plan.Actions))
+		for _, action := range plan.Actions {
+			fmt.Fprintf(w, "  %s %s [item %s]\n", getPlanActionSymbol(action.Kind), action.Description, action.ItemID)
+		}
+		total += len(plan.Actions)
+	}
+
+	if total == 0 {
+		_, err := fmt.Fprintln(w, "no changes, GitHub and Jira are in sync")
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d change(s) pending\n", total)
+	return err
+}
+
+func getPlanActionSymbol(kind PlanActionKind) string {
+	switch kind {
+	case PLAN_ACTION_JIRA_CREATE:
+		return "+"
+	case PLAN_ACTION_JIRA_LINK:
+		return "="
+	default:
+		return "~"
+	}
+}
@@ -0,0 +1,287 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// CONFIG_WATCH_INTERVAL is how often the config file is checked for changes.
+const CONFIG_WATCH_INTERVAL = 5 * time.Second
+
+// syncState holds the config and the projects statuses of a running sync,
+// both are replaced when the config is reloaded. Statuses are sorted as the
+// config projects.
+type syncState struct {
+	mu       sync.RWMutex
+	config   Config
+	statuses []*projectStatus
+	version  int // incremented on every reload
+}
+
+func newSyncState(config Config, statuses []*projectStatus) *syncState {
+	return &syncState{config: config, statuses: statuses}
+}
+
+func (s *syncState) get() (Config, []*projectStatus) {
+	config, statuses, _ := s.getVersion()
+	return config, statuses
+}
+
+func (s *syncState) getVersion() (Config, []*projectStatus, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config, s.statuses, s.version
+}
+
+func (s *syncState) set(config Config, statuses []*projectStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+	s.statuses = statuses
+	s.version++
+}
+
+// projectRunner is a project sync loop started by the sync daemon.
+type projectRunner struct {
+	config  Config // config the loop was started with
+	projPos int
+	status  *projectStatus
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// syncDaemon runs a sync loop per project and starts, stops or restarts
+// them when the config is reloaded, projects are matched by name.
+type syncDaemon struct {
+	ctx       context.Context
+	args      Cmd
+	m         *models.Models
+	ghs       *githubClients
+	state     *syncState
+	log       *logrus.Logger
+	supervise func(ctx context.Context, config Config, projPos int, status *projectStatus)
+
+	mu      sync.Mutex
+	runners map[string]*projectRunner
+	running int
+	idle    chan struct{} // closed once every loop is done
+}
+
+// startSyncDaemon starts the sync loop of every project in the config.
+func startSyncDaemon(ctx context.Context, args Cmd, config Config, m *models.Models, ghs *githubClients, log *logrus.Logger) *syncDaemon {
+	d := &syncDaemon{
+		ctx:     ctx,
+		args:    args,
+		m:       m,
+		ghs:     ghs,
+		log:     log,
+		runners: map[string]*projectRunner{},
+		idle:    make(chan struct{}),
+	}
+	d.supervise = func(ctx context.Context, config Config, projPos int, status *projectStatus) {
+		superviseProject(ctx, d.args, config, projPos, d.m, d.ghs, status, d.log)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state = newSyncState(config, d.reconcile(config, false))
+	if d.running == 0 {
+		close(d.idle)
+	}
+
+	return d
+}
+
+// wait waits until every sync loop is done, reloading the config when its
+// file changes or a SIGHUP is received.
+func (d *syncDaemon) wait(configPath string) {
+	go d.watchConfig(configPath)
+	<-d.idle
+}
+
+// reload reads the config file again and reconciles the sync loops with it,
+// an invalid config is logged and the running one kept.
+func (d *syncDaemon) reload(configPath string) {
+	config, err := readConfig(configPath, d.log)
+	if err != nil {
+		d.log.WithFields(logrus.Fields{"err": err, "config": configPath}).Errorln("config reload failed, keeping the running config")
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-d.idle:
+		return
+	default:
+	}
+
+	current, _ := d.state.get()
+	if fields := getRestartOnlyChanges(current, config); len(fields) > 0 {
+		d.log.WithFields(logrus.Fields{"properties": fields}).Warnln("changed config properties require a restart to take effect")
+	}
+
+	statuses := d.reconcile(config, true)
+	d.state.set(config, statuses)
+	d.log.WithFields(logrus.Fields{"config": configPath}).Infoln("config reloaded")
+}
+
+// reconcile stops the loops of the projects that were removed or changed
+// and starts the ones of new or changed projects, as well as the loops that
+// are done (i.e. gave up), the running loops are kept. It returns the
+// projects statuses sorted as the config projects and must be called with
+// d.mu held.
+func (d *syncDaemon) reconcile(config Config, reloading bool) []*projectStatus {
+	for name, runner := range d.runners {
+		projPos := getProjectPosByName(config, name)
+		if projPos != -1 && runner.isDone() {
+			delete(d.runners, name)
+			d.log.WithFields(logrus.Fields{"project": name}).Infoln("project sync loop was done, restarting it")
+			continue
+		}
+		if projPos != -1 && isSameProjectConfig(runner.config, runner.projPos, config, projPos) {
+			continue
+		}
+
+		runner.cancel()
+		<-runner.done
+		delete(d.runners, name)
+		if projPos == -1 {
+			d.log.WithFields(logrus.Fields{"project": name}).Infoln("project removed from config, sync loop stopped")
+		} else {
+			d.log.WithFields(logrus.Fields{"project": name}).Infoln("project config changed, restarting sync loop")
+		}
+	}
+
+	statuses := make([]*projectStatus, len(config.Projects))
+	for i, proj := range config.Projects {
+		if runner, ok := d.runners[proj.Name]; ok {
+			statuses[i] = runner.status
+			continue
+		}
+
+		statuses[i] = newProjectStatus(proj.Name)
+		// the credentials of the projects in the config read at startup are
+		// checked before starting the daemon
+		if reloading {
+			if _, err := d.ghs.get(proj.Name); err != nil {
+				d.log.WithFields(logrus.Fields{"err": err, "project": proj.Name}).Errorln("getting project github token failed, project sync loop not started")
+				statuses[i].failed(err)
+				statuses[i].giveUp()
+				continue
+			}
+		}
+		d.runners[proj.Name] = d.start(config, i, statuses[i])
+		if reloading {
+			d.log.WithFields(logrus.Fields{"project": proj.Name}).Infoln("project sync loop started")
+		}
+	}
+
+	return statuses
+}
+
+// start runs the project sync loop until it's done or stopped.
+func (d *syncDaemon) start(config Config, projPos int, status *projectStatus) *projectRunner {
+	ctx, cancel := context.WithCancel(d.ctx)
+	runner := &projectRunner{config: config, projPos: projPos, status: status, cancel: cancel, done: make(chan struct{})}
+
+	d.running++
+	go func() {
+		d.supervise(ctx, config, projPos, status)
+		close(runner.done)
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.running--
+		if d.running == 0 {
+			close(d.idle)
+		}
+	}()
+
+	return runner
+}
+
+// isDone reports whether the runner sync loop is done.
+func (r *projectRunner) isDone() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// watchConfig reloads the config when a SIGHUP is received or when the
+// content of the config file or of the files it includes changes. Files
+// are polled, so replacing them (as editors and mounted config maps do) is
+// noticed as well.
+func (d *syncDaemon) watchConfig(configPath string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(CONFIG_WATCH_INTERVAL)
+	defer ticker.Stop()
+
+	last, _ := getConfigHash(configPath)
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-d.idle:
+			return
+		case <-hup:
+			d.log.Infoln("SIGHUP received, reloading config")
+			last, _ = getConfigHash(configPath)
+			d.reload(configPath)
+		case <-ticker.C:
+			hash, err := getConfigHash(configPath)
+			if err != nil || hash == last {
+				continue
+			}
+			last = hash
+			d.log.WithFields(logrus.Fields{"config": configPath}).Infoln("config file changed, reloading config")
+			d.reload(configPath)
+		}
+	}
+}
+
+// isSameProjectConfig reports whether a project and the global properties
+// its sync loop depends on are unchanged.
+func isSameProjectConfig(a Config, aPos int, b Config, bPos int) bool {
+	return reflect.DeepEqual(a.Projects[aPos], b.Projects[bPos]) &&
+		reflect.DeepEqual(a.SleepTime, b.SleepTime) &&
+		reflect.DeepEqual(a.ErrorBudget, b.ErrorBudget)
+}
+
+// getRestartOnlyChanges returns the changed config properties that are only
+// read at startup.
+func getRestartOnlyChanges(a, b Config) []string {
+	fields := []struct {
+		name string
+		a, b any
+	}{
+		{"enableApi", a.EnableAPI, b.EnableAPI},
+		{"apiAddress", a.APIAddress, b.APIAddress},
+		{"enableMetrics", a.EnableMetrics, b.EnableMetrics},
+		{"enableHealth", a.EnableHealth, b.EnableHealth},
+		{"metricsAddress", a.MetricsAddress, b.MetricsAddress},
+		{"database", a.Database, b.Database},
+	}
+
+	changed := []string{}
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.a, f.b) {
+			changed = append(changed, f.name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// newTestSyncDaemon returns a daemon whose project loops report their start
+// and run until stopped, except the ones of the projects in exiting that
+// return right away as a loop that gave up.
+func newTestSyncDaemon(t *testing.T, config Config, exiting map[string]bool) (*syncDaemon, chan string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	starts := make(chan string, 16)
+	d := &syncDaemon{
+		ctx:     ctx,
+		ghs:     newGithubClients(Cmd{}),
+		log:     newLogger(logrus.PanicLevel),
+		runners: map[string]*projectRunner{},
+		idle:    make(chan struct{}),
+	}
+	d.supervise = func(ctx context.Context, config Config, projPos int, status *projectStatus) {
+		starts <- config.Projects[projPos].Name
+		if exiting[config.Projects[projPos].Name] {
+			status.giveUp()
+			return
+		}
+		<-ctx.Done()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state = newSyncState(config, d.reconcile(config, false))
+	return d, starts
+}
+
+func newTestConfig(t *testing.T, content string) Config {
+	t.Helper()
+	var config Config
+	if err := yaml.Unmarshal([]byte(content), &config); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+// waitForStarts returns the names of the project loops started within a
+// short delay.
+func waitForStarts(starts chan string) map[string]int {
+	started := map[string]int{}
+	for {
+		select {
+		case name := <-starts:
+			started[name]++
+		case <-time.After(50 * time.Millisecond):
+			return started
+		}
+	}
+}
+
+func TestSyncDaemonReconcile(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN_a", "token")
+	t.Setenv("GITHUB_TOKEN_b", "token")
+	t.Setenv("GITHUB_TOKEN_c", "token")
+	t.Setenv("GITHUB_TOKEN_gaveup", "token")
+	t.Setenv("GITHUB_TOKEN_nocreds", "")
+
+	config := newTestConfig(t, `
+sync:
+  - name: a
+    jira:
+      projectKey: A
+  - name: b
+    jira:
+      projectKey: B
+  - name: gaveup
+    jira:
+      projectKey: G
+`)
+	d, starts := newTestSyncDaemon(t, config, map[string]bool{"gaveup": true})
+	if started := waitForStarts(starts); len(started) != 3 {
+		t.Fatalf("got started loops %v, want a, b and gaveup", started)
+	}
+	runnerB := d.runners["b"]
+
+	// a is changed, b is kept, c is added and nocreds lacks of credentials
+	reloaded := newTestConfig(t, `
+sync:
+  - name: a
+    jira:
+      projectKey: A2
+  - name: b
+    jira:
+      projectKey: B
+  - name: gaveup
+    jira:
+      projectKey: G
+  - name: c
+    jira:
+      projectKey: C
+  - name: nocreds
+    jira:
+      projectKey: N
+`)
+	d.mu.Lock()
+	statuses := d.reconcile(reloaded, true)
+	d.mu.Unlock()
+
+	started := waitForStarts(starts)
+	want := map[string]int{"a": 1, "gaveup": 1, "c": 1}
+	if len(started) != len(want) {
+		t.Fatalf("got restarted loops %v, want %v", started, want)
+	}
+	for name, n := range want {
+		if started[name] != n {
+			t.Errorf("got restarted loops %v, want %v", started, want)
+		}
+	}
+	if d.runners["b"] != runnerB {
+		t.Error("got the unchanged project loop restarted, want it kept")
+	}
+	if _, ok := d.runners["nocreds"]; ok {
+		t.Error("got the project without credentials started, want it refused")
+	}
+
+	if len(statuses) != 5 {
+		t.Fatalf("got %d statuses, want one per project", len(statuses))
+	}
+	if statuses[4].Err() == nil {
+		t.Error("got no error for the project without credentials, want the credentials one")
+	}
+	if statuses[1] != runnerB.status {
+		t.Error("got a new status for the unchanged project, want it kept")
+	}
+
+	// removed projects are stopped
+	d.mu.Lock()
+	d.reconcile(newTestConfig(t, "sync: []"), true)
+	d.mu.Unlock()
+	if len(d.runners) != 0 {
+		t.Errorf("got runners %v, want none", d.runners)
+	}
+}
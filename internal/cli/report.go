@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/report"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	REPORT_FORMAT_CSV      = "csv"
+	REPORT_FORMAT_MARKDOWN = "markdown"
+	REPORT_FORMAT_JSON     = "json"
+)
+
+type ReportCmd struct {
+	CycleTime *ReportCycleTimeCmd `arg:"subcommand:cycle-time" help:"lead time, cycle time and time in status of the synced items"`
+}
+
+type ReportCycleTimeCmd struct {
+	Config  string  `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project *string `arg:"--project" help:"only report the items of the given sync project" placeholder:"<NAME>"`
+	Since   *string `arg:"--since" help:"only report the items done since a duration ago (i.e. 720h) or a RFC3339 time" placeholder:"<SINCE>"`
+	GroupBy string  `arg:"--group-by" default:"item" help:"group items by [item, repository, issue-type, assignee]" placeholder:"<GROUP>"`
+	Format  string  `arg:"--format" default:"markdown" help:"output format [csv, markdown, json]" placeholder:"<FORMAT>"`
+}
+
+// ReportCycleTimeAction prints the cycle time report of the synced items,
+// computed from the issues status history recorded by the sync.
+func ReportCycleTimeAction(args Cmd) {
+	if args.Report == nil || args.Report.CycleTime == nil {
+		exitOnInvalidCall("report cycle-time")
+	}
+	cmd := args.Report.CycleTime
+
+	switch cmd.GroupBy {
+	case report.GROUP_BY_ITEM, report.GROUP_BY_REPOSITORY, report.GROUP_BY_ISSUE_TYPE, report.GROUP_BY_ASSIGNEE:
+	default:
+		err := fmt.Errorf(`"--group-by" should be one of [%s, %s, %s, %s]`, report.GROUP_BY_ITEM, report.GROUP_BY_REPOSITORY, report.GROUP_BY_ISSUE_TYPE, report.GROUP_BY_ASSIGNEE)
+		exitFromErr(err)
+	}
+	switch cmd.Format {
+	case REPORT_FORMAT_CSV, REPORT_FORMAT_MARKDOWN, REPORT_FORMAT_JSON:
+	default:
+		err := fmt.Errorf(`"--format" should be one of [%s, %s, %s]`, REPORT_FORMAT_CSV, REPORT_FORMAT_MARKDOWN, REPORT_FORMAT_JSON)
+		exitFromErr(err)
+	}
+
+	log := newLogger(logrus.InfoLevel)
+	config, err := readConfig(cmd.Config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	var since time.Time
+	if cmd.Since != nil {
+		if d, err := time.ParseDuration(*cmd.Since); err == nil {
+			since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, *cmd.Since); err == nil {
+			since = t
+		} else {
+			exitFromErr(fmt.Errorf(`since "%s" is neither a duration nor a RFC3339 time`, *cmd.Since))
+		}
+	}
+
+	projects := config.Projects
+	if cmd.Project != nil {
+		projPos := getProjectPosByName(config, *cmd.Project)
+		if projPos == -1 {
+			exitFromErr(fmt.Errorf(`sync project "%s" not found in config`, *cmd.Project))
+		}
+		projects = config.Projects[projPos : projPos+1]
+	}
+
+	m, err := initializeModels(args, cmd.Config, config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	defer m.Close()
+
+	now := time.Now().UTC()
+	items := []report.ItemCycleTime{}
+	for _, proj := range projects {
+		changes, err := m.History.GetAll(proj.Github.ProjectID)
+		if err != nil {
+			exitFromErr(err)
+		}
+		for _, item := range report.GetItemsCycleTime(changes, now) {
+			if !since.IsZero() && (item.DoneAt == nil || item.DoneAt.Before(since)) {
+				continue
+			}
+			items = append(items, item)
+		}
+	}
+
+	if err := writeCycleTimeReport(os.Stdout, config, items, cmd.GroupBy, cmd.Format); err != nil {
+		exitFromErr(err)
+	}
+}
+
+// writeCycleTimeReport writes the items cycle time report in the given
+// format, durations are written in hours.
+func writeCycleTimeReport(w io.Writer, config Config, items []report.ItemCycleTime, groupBy, format string) error {
+	statuses := report.GetStatuses(items)
+
+	var header []string
+	var rows [][]string
+	var result any
+	if groupBy == report.GROUP_BY_ITEM {
+		header, rows, result = getItemsReport(config, items, statuses)
+	} else {
+		header, rows, result = getGroupsReport(report.GroupItems(items, groupBy), statuses)
+	}
+
+	switch format {
+	case REPORT_FORMAT_JSON:
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case REPORT_FORMAT_CSV:
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.WriteAll(rows)
+		return cw.Error()
+	default:
+		fmt.Fprintf(w, "| %s |\n", strings.Join(header, " | "))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(header)))
+		for _, row := range rows {
+			for i := range row {
+				row[i] = strings.ReplaceAll(row[i], "|", `\|`)
+			}
+			if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | ")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// ItemCycleTimeReport is the json representation of an item cycle time.
+type ItemCycleTimeReport struct {
+	Project           *string            `json:"project"`
+	GitHubProjectID   string             `json:"githubProjectId"`
+	GitHubItemID      string             `json:"githubItemId"`
+	Title             string             `json:"title"`
+	Status            string             `json:"status"`
+	JiraIssueType     *string            `json:"jiraIssueType"`
+	Repository        *string            `json:"repository"`
+	Assignees         []string           `json:"assignees"`
+	FirstSeenAt       time.Time          `json:"firstSeenAt"`
+	StartedAt         *time.Time         `json:"startedAt"`
+	DoneAt            *time.Time         `json:"doneAt"`
+	LeadTimeHours     *float64           `json:"leadTimeHours"`
+	CycleTimeHours    *float64           `json:"cycleTimeHours"`
+	TimeInStatusHours map[string]float64 `json:"timeInStatusHours"`
+}
+
+// GroupCycleTimeReport is the json representation of a group cycle time.
+type GroupCycleTimeReport struct {
+	Group                string             `json:"group"`
+	Items                int                `json:"items"`
+	Done                 int                `json:"done"`
+	AvgLeadTimeHours     *float64           `json:"avgLeadTimeHours"`
+	MedianLeadTimeHours  *float64           `json:"medianLeadTimeHours"`
+	AvgCycleTimeHours    *float64           `json:"avgCycleTimeHours"`
+	MedianCycleTimeHours *float64           `json:"medianCycleTimeHours"`
+	AvgTimeInStatusHours map[string]float64 `json:"avgTimeInStatusHours"`
+}
+
+func getItemsReport(config Config, items []report.ItemCycleTime, statuses []string) ([]string, [][]string, []ItemCycleTimeReport) {
+	header := []string{"project", "item", "title", "repository", "issue type", "assignees", "status", "lead time (h)", "cycle time (h)"}
+	for _, status := range statuses {
+		header = append(header, status+" (h)")
+	}
+
+	rows := [][]string{}
+	result := []ItemCycleTimeReport{}
+	for _, item := range items {
+		r := ItemCycleTimeReport{
+			GitHubProjectID:   item.GitHubProjectID,
+			GitHubItemID:      item.GitHubID,
+			Title:             item.Title,
+			Status:            item.Status,
+			JiraIssueType:     item.JiraIssueType,
+			Repository:        item.Repository,
+			Assignees:         item.Assignees,
+			FirstSeenAt:       item.FirstSeenAt,
+			StartedAt:         item.StartedAt,
+			DoneAt:            item.DoneAt,
+			LeadTimeHours:     toHours(item.LeadTime),
+			CycleTimeHours:    toHours(item.CycleTime),
+			TimeInStatusHours: toHoursMap(item.TimeInStatus),
+		}
+		if projPos := getProjectPosById(config, item.GitHubProjectID); projPos != -1 {
+			r.Project = &config.Projects[projPos].Name
+		}
+		result = append(result, r)
+
+		row := []string{
+			derefOr(r.Project, r.GitHubProjectID),
+			r.GitHubItemID,
+			r.Title,
+			deref(r.Repository),
+			deref(r.JiraIssueType),
+			strings.Join(r.Assignees, ";"),
+			r.Status,
+			formatHours(r.LeadTimeHours),
+			formatHours(r.CycleTimeHours),
+		}
+		rows = append(rows, append(row, formatStatusHours(r.TimeInStatusHours, statuses)...))
+	}
+
+	return header, rows, result
+}
+
+func getGroupsReport(groups []report.GroupCycleTime, statuses []string) ([]string, [][]string, []GroupCycleTimeReport) {
+	header := []string{"group", "items", "done", "avg lead time (h)", "median lead time (h)", "avg cycle time (h)", "median cycle time (h)"}
+	for _, status := range statuses {
+		header = append(header, "avg "+status+" (h)")
+	}
+
+	rows := [][]string{}
+	result := []GroupCycleTimeReport{}
+	for _, group := range groups {
+		r := GroupCycleTimeReport{
+			Group:                group.Group,
+			Items:                group.Items,
+			Done:                 group.Done,
+			AvgLeadTimeHours:     toHours(group.AvgLeadTime),
+			MedianLeadTimeHours:  toHours(group.MedianLeadTime),
+			AvgCycleTimeHours:    toHours(group.AvgCycleTime),
+			MedianCycleTimeHours: toHours(group.MedianCycleTime),
+			AvgTimeInStatusHours: toHoursMap(group.AvgTimeInStatus),
+		}
+		result = append(result, r)
+
+		row := []string{
+			r.Group,
+			strconv.Itoa(r.Items),
+			strconv.Itoa(r.Done),
+			formatHours(r.AvgLeadTimeHours),
+			formatHours(r.MedianLeadTimeHours),
+			formatHours(r.AvgCycleTimeHours),
+			formatHours(r.MedianCycleTimeHours),
+		}
+		rows = append(rows, append(row, formatStatusHours(r.AvgTimeInStatusHours, statuses)...))
+	}
+
+	return header, rows, result
+}
+
+func toHours(d *time.Duration) *float64 {
+	if d == nil {
+		return nil
+	}
+	h := roundHours(*d)
+	return &h
+}
+
+func toHoursMap(m map[string]time.Duration) map[string]float64 {
+	result := map[string]float64{}
+	for status, d := range m {
+		result[status] = roundHours(d)
+	}
+	return result
+}
+
+func roundHours(d time.Duration) float64 {
+	h, _ := strconv.ParseFloat(strconv.FormatFloat(d.Hours(), 'f', 2, 64), 64)
+	return h
+}
+
+func formatHours(h *float64) string {
+	if h == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*h, 'f', 2, 64)
+}
+
+func formatStatusHours(m map[string]float64, statuses []string) []string {
+	result := []string{}
+	for _, status := range statuses {
+		h, ok := m[status]
+		if !ok {
+			result = append(result, "")
+			continue
+		}
+		result = append(result, formatHours(&h))
+	}
+	return result
+}
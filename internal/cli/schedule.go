@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/scheduler"
+	"gopkg.in/yaml.v3"
+)
+
+// ScheduleConfig is the schedule of a sync project, it can be written either
+// as a mapping or as a plain expression (i.e. `schedule: 5m`).
+type ScheduleConfig struct {
+	Expression    string               `yaml:"expression"`
+	Jitter        string               `yaml:"jitter"`
+	Timezone      string               `yaml:"timezone"`
+	ActiveWindows []ActiveWindowConfig `yaml:"activeWindows"`
+}
+
+type ActiveWindowConfig struct {
+	Days []string `yaml:"days"`
+	From string   `yaml:"from"`
+	To   string   `yaml:"to"`
+}
+
+func (s *ScheduleConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		s.Expression = value.Value
+		return nil
+	}
+
+	type plain ScheduleConfig
+	return value.Decode((*plain)(s))
+}
+
+// newProjectSchedule builds the schedule of a sync project. Projects without
+// a schedule fall back to the global "sleepTime", if none of them is set the
+// project runs once and *scheduler.Schedule will be nil.
+func newProjectSchedule(config Config, projPos int) (*scheduler.Schedule, error) {
+	scheduleCfg := config.Projects[projPos].Schedule
+
+	if scheduleCfg == nil {
+		if config.SleepTime != nil && *config.SleepTime >= 0 {
+			return scheduler.Every(time.Duration(*config.SleepTime) * time.Millisecond), nil
+		}
+		return nil, nil
+	}
+
+	if scheduleCfg.Expression == "" {
+		return nil, fmt.Errorf(`"sync[%d].schedule.expression" property is missing`, projPos)
+	}
+
+	var jitter time.Duration
+	if scheduleCfg.Jitter != "" {
+		var err error
+		jitter, err = time.ParseDuration(scheduleCfg.Jitter)
+		if err != nil {
+			return nil, fmt.Errorf(`"sync[%d].schedule.jitter" property is not a valid duration: %w`, projPos, err)
+		}
+	}
+
+	location := time.Local
+	if scheduleCfg.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(scheduleCfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf(`"sync[%d].schedule.timezone" property is not a valid timezone: %w`, projPos, err)
+		}
+	}
+
+	windows := []scheduler.Window{}
+	for i, windowCfg := range scheduleCfg.ActiveWindows {
+		window, err := scheduler.ParseWindow(windowCfg.Days, windowCfg.From, windowCfg.To)
+		if err != nil {
+			return nil, fmt.Errorf(`"sync[%d].schedule.activeWindows[%d]" property is not valid: %w`, projPos, i, err)
+		}
+		windows = append(windows, window)
+	}
+
+	schedule, err := scheduler.New(scheduleCfg.Expression, jitter, location, windows)
+	if err != nil {
+		return nil, fmt.Errorf(`"sync[%d].schedule.expression" property is not valid: %w`, projPos, err)
+	}
+
+	return schedule, nil
+}
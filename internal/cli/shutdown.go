@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"time"
+)
+
+// SHUTDOWN_GRACE_PERIOD is the time in-flight item operations are given to
+// finish once a shutdown is requested, it is kept under docker's default
+// stop timeout (10s).
+const SHUTDOWN_GRACE_PERIOD = 8 * time.Second
+
+type shutdownCtxKey struct{}
+
+// withGracePeriod returns a context that is only cancelled once the grace
+// period passes after the parent is cancelled, so in-flight operations can
+// finish. Use shuttingDown to know whether the parent was cancelled and
+// no new work should be picked up.
+func withGracePeriod(parent context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.WithoutCancel(parent), shutdownCtxKey{}, parent))
+	stop := context.AfterFunc(parent, func() {
+		time.AfterFunc(grace, cancel)
+	})
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// shutdownDone returns a channel that's closed once a shutdown is requested.
+func shutdownDone(ctx context.Context) <-chan struct{} {
+	if parent, ok := ctx.Value(shutdownCtxKey{}).(context.Context); ok {
+		return parent.Done()
+	}
+	return ctx.Done()
+}
+
+// shuttingDown reports whether a shutdown was requested.
+func shuttingDown(ctx context.Context) bool {
+	select {
+	case <-shutdownDone(ctx):
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep pauses for the given duration, it wakes up at once when a shutdown
+// is requested and returns false in such case.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-shutdownDone(ctx):
+		return false
+	case <-timer.C:
+		return true
+	}
+}
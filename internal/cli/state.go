@@ -0,0 +1,659 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/github"
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	STATE_FORMAT_TEXT = "text"
+	STATE_FORMAT_JSON = "json"
+	STATE_FORMAT_CSV  = "csv"
+)
+
+type StateCmd struct {
+	Rebuild *StateRebuildCmd `arg:"subcommand:rebuild" help:"rebuild the local issues storage from GitHub and Jira"`
+	List    *StateListCmd    `arg:"subcommand:list" help:"list the stored issues of a sync project"`
+	Show    *StateShowCmd    `arg:"subcommand:show" help:"show a stored issue along with its status history"`
+	Export  *StateExportCmd  `arg:"subcommand:export" help:"export the stored issues"`
+	Import  *StateImportCmd  `arg:"subcommand:import" help:"import stored issues from an export"`
+}
+
+type StateListCmd struct {
+	Config   string  `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project  string  `arg:"required,--project" help:"sync project name" placeholder:"<NAME>"`
+	Status   *string `arg:"--status" help:"only list the issues with the given status (i.e. \"In Progress\")" placeholder:"<STATUS>"`
+	Unlinked bool    `arg:"--unlinked" help:"only list the issues without a jira issue"`
+	Format   string  `arg:"--format" default:"text" help:"output format [text, json]" placeholder:"<FORMAT>"`
+}
+
+type StateShowCmd struct {
+	ItemID  string  `arg:"positional,required" help:"GitHub project item id" placeholder:"ITEM_ID"`
+	Config  string  `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project *string `arg:"--project" help:"sync project name, every sync project is searched if not given" placeholder:"<NAME>"`
+	Format  string  `arg:"--format" default:"text" help:"output format [text, json]" placeholder:"<FORMAT>"`
+}
+
+type StateExportCmd struct {
+	Config  string  `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project *string `arg:"--project" help:"only export the given sync project" placeholder:"<NAME>"`
+	Format  string  `arg:"--format" default:"json" help:"output format [json, csv]" placeholder:"<FORMAT>"`
+	Output  *string `arg:"--output,-o" help:"file to write the export to (defaults to stdout)" placeholder:"<PATH>"`
+}
+
+type StateImportCmd struct {
+	File    string  `arg:"positional,required" help:"export file, \"-\" reads stdin" placeholder:"FILE"`
+	Config  string  `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project *string `arg:"--project" help:"only import the issues of the given sync project" placeholder:"<NAME>"`
+	Format  *string `arg:"--format" help:"input format [json, csv] (defaults to the file extension, or json)" placeholder:"<FORMAT>"`
+}
+
+// stateIssueShow is the json representation of a stored issue along with
+// its status history and tombstone.
+type stateIssueShow struct {
+	Project   string                 `json:"project"`
+	Issue     *apiIssue              `json:"issue"`
+	Tombstone *stateTombstone        `json:"tombstone"`
+	History   []stateIssueStatusItem `json:"history"`
+}
+
+type stateTombstone struct {
+	Action    string    `json:"action"`
+	RemovedAt time.Time `json:"removedAt"`
+}
+
+type stateIssueStatusItem struct {
+	Status    string    `json:"status"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// STATE_CSV_HEADER are the columns of the csv exports.
+var STATE_CSV_HEADER = []string{
+	"githubProjectId",
+	"githubItemId",
+	"title",
+	"status",
+	"jiraUrl",
+	"jiraIssueType",
+	"estimate",
+	"assignees",
+	"repository",
+}
+
+type StateRebuildCmd struct {
+	Config  string  `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Project *string `arg:"--project" help:"only rebuild the given sync project" placeholder:"<NAME>"`
+}
+
+// StateRebuildAction rebuilds the local issues storage from the GitHub
+// projects items and the jira issues stamped with their item id.
+func StateRebuildAction(args Cmd) {
+	if args.State == nil || args.State.Rebuild == nil {
+		exitOnInvalidCall("state rebuild")
+	}
+
+	level := logrus.InfoLevel
+	if args.Debug != nil && *args.Debug == true {
+		level = logrus.DebugLevel
+	}
+	log := newLogger(level)
+
+	config, err := readConfig(args.State.Rebuild.Config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	m, err := initializeModels(args, args.State.Rebuild.Config, config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+	ghs := newGithubClients(args)
+	found := false
+	for i := 0; i < len(config.Projects); i++ {
+		if args.State.Rebuild.Project != nil && *args.State.Rebuild.Project != config.Projects[i].Name {
+			continue
+		}
+		found = true
+
+		gh, err := ghs.get(config.Projects[i].Name)
+		if err != nil {
+			exitFromErr(err)
+		}
+		if err := rebuildProjectState(ctx, args, config, i, m, gh, log); err != nil {
+			exitFromErr(err)
+		}
+	}
+
+	if args.State.Rebuild.Project != nil && !found {
+		err := fmt.Errorf(`sync project "%s" not found in config`, *args.State.Rebuild.Project)
+		exitFromErr(err)
+	}
+}
+
+func rebuildProjectState(ctx context.Context, args Cmd, config Config, projPos int, m *models.Models, gh *github.GitHubClient, log *logrus.Logger) error {
+	projectCfg := config.Projects[projPos]
+
+	jc, err := newProjectJiraClient(args, config, projPos)
+	if err != nil {
+		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("failed creating jira client")
+		return err
+	}
+
+	p, err := upsertProjectFields(ctx, config, projPos, m, gh, log)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(logrus.Fields{"project": projectCfg.Name}).Infoln("querying gh remote issues")
+	remoteIssues, _, err := fetchRemoteIssues(ctx, gh, p.ID)
+	if err != nil {
+		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("querying gh remote issues failed")
+		return err
+	}
+	remoteIssues = filterSyncableIssues(remoteIssues)
+
+	linked := 0
+	recovered := 0
+	for _, ri := range remoteIssues {
+		is := ri.ToIssue(p.ID)
+
+		if is.JiraURL == nil {
+			key, err := findJiraIssueByItem(ctx, jc, config, projPos, p.ID, is.GitHubID)
+			if err != nil {
+				log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": is.GitHubID}).Errorln("searching jira issue by item failed")
+				return err
+			}
+
+			if key != "" {
+				url := getJiraIssueUrl(config, projPos, key)
+				log.WithFields(logrus.Fields{"project": projectCfg.Name, "itemId": is.GitHubID, "jiraUrl": url}).Infoln("recovered jira issue from item label")
+				if err := writeJiraUrlToGithub(ctx, gh, *p, is.GitHubID, key, url); err != nil {
+					log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": is.GitHubID}).Errorln("writing jira url into github failed")
+					return err
+				}
+				is.JiraURL = &url
+				recovered++
+			}
+		}
+
+		if is.JiraURL != nil {
+			linked++
+		}
+
+		if _, err := p.UpsertIssue(
+			is.GitHubID,
+			is.Title,
+			is.Status,
+			is.JiraURL,
+			is.JiraIssueType,
+			is.Repository,
+			is.Estimate,
+			&is.Assignees,
+		); err != nil {
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": is.GitHubID}).Errorln("failed to upsert issue")
+			return err
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"project":   projectCfg.Name,
+		"items":     len(remoteIssues),
+		"linked":    linked,
+		"recovered": recovered,
+	}).Infoln("project state rebuilt")
+
+	return nil
+}
+
+// StateListAction prints the stored issues of a sync project.
+func StateListAction(args Cmd) {
+	if args.State == nil || args.State.List == nil {
+		exitOnInvalidCall("state list")
+	}
+	cmd := args.State.List
+
+	if cmd.Format != STATE_FORMAT_TEXT && cmd.Format != STATE_FORMAT_JSON {
+		err := fmt.Errorf(`"--format" should be one of [%s, %s]`, STATE_FORMAT_TEXT, STATE_FORMAT_JSON)
+		exitFromErr(err)
+	}
+
+	log := newLogger(logrus.InfoLevel)
+	config, err := readConfig(cmd.Config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	projPos := getProjectPosByName(config, cmd.Project)
+	if projPos == -1 {
+		exitFromErr(fmt.Errorf(`sync project "%s" not found in config`, cmd.Project))
+	}
+
+	m, err := openModelsReadOnly(args, cmd.Config, config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	defer m.Close()
+
+	projectId := config.Projects[projPos].Github.ProjectID
+	var issues []*models.Issue
+	if cmd.Unlinked {
+		issues, err = m.Issues.GetWithoutUrl(projectId)
+	} else {
+		issues, err = m.Issues.GetAll(projectId)
+	}
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	result := []apiIssue{}
+	for _, is := range issues {
+		if cmd.Status != nil && (is.Status == nil || string(*is.Status) != *cmd.Status) {
+			continue
+		}
+		result = append(result, newApiIssue(*is))
+	}
+
+	if cmd.Format == STATE_FORMAT_JSON {
+		if err := writeJSONTo(os.Stdout, result); err != nil {
+			exitFromErr(err)
+		}
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ITEM\tSTATUS\tJIRA KEY\tISSUE TYPE\tTITLE")
+	for _, is := range result {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			is.GitHubItemID,
+			derefOr(is.Status, "-"),
+			derefOr(is.JiraKey, "-"),
+			derefOr(is.JiraIssueType, "-"),
+			is.Title,
+		)
+	}
+	tw.Flush()
+}
+
+// StateShowAction prints a stored issue along with its status history and
+// its tombstone, if the item was removed from the GitHub project.
+func StateShowAction(args Cmd) {
+	if args.State == nil || args.State.Show == nil {
+		exitOnInvalidCall("state show")
+	}
+	cmd := args.State.Show
+
+	if cmd.Format != STATE_FORMAT_TEXT && cmd.Format != STATE_FORMAT_JSON {
+		err := fmt.Errorf(`"--format" should be one of [%s, %s]`, STATE_FORMAT_TEXT, STATE_FORMAT_JSON)
+		exitFromErr(err)
+	}
+
+	log := newLogger(logrus.InfoLevel)
+	config, err := readConfig(cmd.Config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	if cmd.Project != nil && getProjectPosByName(config, *cmd.Project) == -1 {
+		exitFromErr(fmt.Errorf(`sync project "%s" not found in config`, *cmd.Project))
+	}
+
+	m, err := openModelsReadOnly(args, cmd.Config, config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	defer m.Close()
+
+	var show *stateIssueShow
+	for _, proj := range config.Projects {
+		if cmd.Project != nil && *cmd.Project != proj.Name {
+			continue
+		}
+		show, err = getStateIssueShow(m, proj.Name, proj.Github.ProjectID, cmd.ItemID)
+		if err != nil {
+			exitFromErr(err)
+		}
+		if show != nil {
+			break
+		}
+	}
+	if show == nil {
+		exitFromErr(fmt.Errorf(`item "%s" is not stored`, cmd.ItemID))
+	}
+
+	if cmd.Format == STATE_FORMAT_JSON {
+		if err := writeJSONTo(os.Stdout, show); err != nil {
+			exitFromErr(err)
+		}
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "project:\t%s\n", show.Project)
+	if is := show.Issue; is != nil {
+		estimate := "-"
+		if is.Estimate != nil {
+			estimate = strconv.Itoa(*is.Estimate)
+		}
+		fmt.Fprintf(tw, "github project:\t%s\n", is.GitHubProjectID)
+		fmt.Fprintf(tw, "item:\t%s\n", is.GitHubItemID)
+		fmt.Fprintf(tw, "title:\t%s\n", is.Title)
+		fmt.Fprintf(tw, "status:\t%s\n", derefOr(is.Status, "-"))
+		fmt.Fprintf(tw, "jira url:\t%s\n", derefOr(is.JiraURL, "-"))
+		fmt.Fprintf(tw, "jira issue type:\t%s\n", derefOr(is.JiraIssueType, "-"))
+		fmt.Fprintf(tw, "estimate:\t%s\n", estimate)
+		fmt.Fprintf(tw, "assignees:\t%s\n", strings.Join(is.Assignees, ", "))
+		fmt.Fprintf(tw, "repository:\t%s\n", derefOr(is.Repository, "-"))
+	}
+	if show.Tombstone != nil {
+		fmt.Fprintf(tw, "removed at:\t%s (%s)\n", show.Tombstone.RemovedAt.Local().Format(time.DateTime), show.Tombstone.Action)
+	}
+	fmt.Fprintln(tw, "history:\t")
+	for _, change := range show.History {
+		fmt.Fprintf(tw, "  %s\t%s\n", change.ChangedAt.Local().Format(time.DateTime), change.Status)
+	}
+	tw.Flush()
+}
+
+// getStateIssueShow retrieves a stored issue of a project, along with its
+// history and tombstone. It returns nil if the item is neither stored nor
+// tombstoned.
+func getStateIssueShow(m *models.Models, projectName, projectId, itemId string) (*stateIssueShow, error) {
+	is, err := m.Issues.Get(projectId, itemId)
+	if err != nil {
+		return nil, err
+	}
+	tombstone, err := m.Tombstones.Get(projectId, itemId)
+	if err != nil {
+		return nil, err
+	}
+	if is == nil && tombstone == nil {
+		return nil, nil
+	}
+
+	show := &stateIssueShow{Project: projectName, History: []stateIssueStatusItem{}}
+	if is != nil {
+		issue := newApiIssue(*is)
+		show.Issue = &issue
+	}
+	if tombstone != nil {
+		show.Tombstone = &stateTombstone{Action: tombstone.Action, RemovedAt: tombstone.RemovedAt}
+	}
+
+	changes, err := m.History.GetByItem(projectId, itemId)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		show.History = append(show.History, stateIssueStatusItem{Status: change.Status, ChangedAt: change.ChangedAt})
+	}
+
+	return show, nil
+}
+
+// StateExportAction writes the stored issues of the configured sync
+// projects as json or csv, they can be loaded back with "state import".
+func StateExportAction(args Cmd) {
+	if args.State == nil || args.State.Export == nil {
+		exitOnInvalidCall("state export")
+	}
+	cmd := args.State.Export
+
+	if cmd.Format != STATE_FORMAT_JSON && cmd.Format != STATE_FORMAT_CSV {
+		err := fmt.Errorf(`"--format" should be one of [%s, %s]`, STATE_FORMAT_JSON, STATE_FORMAT_CSV)
+		exitFromErr(err)
+	}
+
+	log := newLogger(logrus.InfoLevel)
+	config, err := readConfig(cmd.Config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	if cmd.Project != nil && getProjectPosByName(config, *cmd.Project) == -1 {
+		exitFromErr(fmt.Errorf(`sync project "%s" not found in config`, *cmd.Project))
+	}
+
+	m, err := openModelsReadOnly(args, cmd.Config, config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	defer m.Close()
+
+	result := []apiIssue{}
+	for _, proj := range config.Projects {
+		if cmd.Project != nil && *cmd.Project != proj.Name {
+			continue
+		}
+		issues, err := m.Issues.GetAll(proj.Github.ProjectID)
+		if err != nil {
+			exitFromErr(err)
+		}
+		for _, is := range issues {
+			result = append(result, newApiIssue(*is))
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if cmd.Output != nil {
+		f, err := os.Create(*cmd.Output)
+		if err != nil {
+			exitFromErr(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if cmd.Format == STATE_FORMAT_CSV {
+		err = writeStateCSV(w, result)
+	} else {
+		err = writeJSONTo(w, result)
+	}
+	if err != nil {
+		exitFromErr(err)
+	}
+}
+
+// StateImportAction upserts the issues of a "state export" into the local
+// storage. Issues of GitHub projects that are not configured are refused.
+func StateImportAction(args Cmd) {
+	if args.State == nil || args.State.Import == nil {
+		exitOnInvalidCall("state import")
+	}
+	cmd := args.State.Import
+
+	format := STATE_FORMAT_JSON
+	if cmd.Format != nil {
+		format = *cmd.Format
+	} else if strings.EqualFold(filepath.Ext(cmd.File), ".csv") {
+		format = STATE_FORMAT_CSV
+	}
+	if format != STATE_FORMAT_JSON && format != STATE_FORMAT_CSV {
+		err := fmt.Errorf(`"--format" should be one of [%s, %s]`, STATE_FORMAT_JSON, STATE_FORMAT_CSV)
+		exitFromErr(err)
+	}
+
+	log := newLogger(logrus.InfoLevel)
+	config, err := readConfig(cmd.Config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	var projectId *string
+	if cmd.Project != nil {
+		projPos := getProjectPosByName(config, *cmd.Project)
+		if projPos == -1 {
+			exitFromErr(fmt.Errorf(`sync project "%s" not found in config`, *cmd.Project))
+		}
+		projectId = &config.Projects[projPos].Github.ProjectID
+	}
+
+	var r io.Reader = os.Stdin
+	if cmd.File != "-" {
+		f, err := os.Open(cmd.File)
+		if err != nil {
+			exitFromErr(err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var issues []apiIssue
+	if format == STATE_FORMAT_CSV {
+		issues, err = readStateCSV(r)
+	} else {
+		err = json.NewDecoder(r).Decode(&issues)
+	}
+	if err != nil {
+		exitFromErr(fmt.Errorf("reading state export failed: %w", err))
+	}
+
+	for i, is := range issues {
+		if is.GitHubProjectID == "" || is.GitHubItemID == "" || is.Title == "" {
+			exitFromErr(fmt.Errorf(`issue %d lacks of "githubProjectId", "githubItemId" or "title"`, i))
+		}
+		if getProjectPosById(config, is.GitHubProjectID) == -1 {
+			exitFromErr(fmt.Errorf(`issue %d github project "%s" is not configured`, i, is.GitHubProjectID))
+		}
+		if is.Status == nil || !models.IssueStatus(*is.Status).IsKnown() {
+			err := fmt.Errorf(`issue %d "status" should be one of [%s, %s, %s]`, i, models.STATUS_TODO, models.STATUS_WIP, models.STATUS_DONE)
+			exitFromErr(err)
+		}
+	}
+
+	m, err := initializeModels(args, cmd.Config, config, log)
+	if err != nil {
+		exitFromErr(err)
+	}
+	defer m.Close()
+
+	imported := 0
+	for _, is := range issues {
+		if projectId != nil && *projectId != is.GitHubProjectID {
+			continue
+		}
+		assignees := is.Assignees
+		if assignees == nil {
+			assignees = []string{}
+		}
+		_, err := m.Issues.Upsert(
+			is.GitHubProjectID,
+			is.GitHubItemID,
+			is.Title,
+			(*models.IssueStatus)(is.Status),
+			is.JiraURL,
+			is.JiraIssueType,
+			is.Repository,
+			is.Estimate,
+			&assignees,
+		)
+		if err != nil {
+			exitFromErr(err)
+		}
+		imported++
+	}
+
+	fmt.Printf("imported %d issues\n", imported)
+}
+
+func writeJSONTo(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// writeStateCSV writes the issues with the STATE_CSV_HEADER columns, empty
+// cells stand for null values and assignees are separated by ";".
+func writeStateCSV(w io.Writer, issues []apiIssue) error {
+	cw := csv.NewWriter(w)
+	cw.Write(STATE_CSV_HEADER)
+	for _, is := range issues {
+		estimate := ""
+		if is.Estimate != nil {
+			estimate = strconv.Itoa(*is.Estimate)
+		}
+		cw.Write([]string{
+			is.GitHubProjectID,
+			is.GitHubItemID,
+			is.Title,
+			deref(is.Status),
+			deref(is.JiraURL),
+			deref(is.JiraIssueType),
+			estimate,
+			strings.Join(is.Assignees, ";"),
+			deref(is.Repository),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readStateCSV reads the issues written by writeStateCSV, columns are
+// matched by their header name.
+func readStateCSV(r io.Reader) ([]apiIssue, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []apiIssue{}, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	for _, name := range STATE_CSV_HEADER {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf(`csv column "%s" is missing`, name)
+		}
+	}
+
+	issues := []apiIssue{}
+	for line, record := range records[1:] {
+		value := func(name string) *string {
+			v := record[columns[name]]
+			if v == "" {
+				return nil
+			}
+			return &v
+		}
+		is := apiIssue{
+			GitHubProjectID: deref(value("githubProjectId")),
+			GitHubItemID:    deref(value("githubItemId")),
+			Title:           deref(value("title")),
+			Status:          value("status"),
+			JiraURL:         value("jiraUrl"),
+			JiraIssueType:   value("jiraIssueType"),
+			Repository:      value("repository"),
+			Assignees:       []string{},
+		}
+		if estimate := value("estimate"); estimate != nil {
+			n, err := strconv.Atoi(*estimate)
+			if err != nil {
+				return nil, fmt.Errorf(`line %d estimate "%s" is not a number`, line+2, *estimate)
+			}
+			is.Estimate = &n
+		}
+		if assignees := value("assignees"); assignees != nil {
+			is.Assignees = strings.Split(*assignees, ";")
+		}
+		issues = append(issues, is)
+	}
+
+	return issues, nil
+}
@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DEFAULT_ERROR_BUDGET  = 5
+	SYNC_RETRY_BASE_DELAY = 5 * time.Second
+	SYNC_RETRY_MAX_DELAY  = 5 * time.Minute
+)
+
+// EXIT_CODE_PARTIAL_FAILURE is the exit code of a sync execution where
+// some projects failed while others succeeded.
+const EXIT_CODE_PARTIAL_FAILURE = 3
+
+// SYNC_REQUESTS_BUFFER is the number of sync requests a project can have
+// queued while it's busy.
+const SYNC_REQUESTS_BUFFER = 16
+
+var (
+	errProjectNotRunning   = errors.New("project sync loop is not running")
+	errTooManySyncRequests = errors.New("too many sync requests queued")
+)
+
+// projectStatus holds the sync status of a project, it is shared between the
+// project sync loop, its supervisor and the management api.
+type projectStatus struct {
+	mu            sync.Mutex
+	name          string
+	failures      int // consecutive failures
+	lastErr       error
+	lastSuccessAt time.Time
+	gaveUp        bool
+	startedAt     time.Time
+	running       bool        // whether the project sync loop is waiting for requests
+	requests      chan string // item ids to be synced, an empty id syncs the whole project
+}
+
+func newProjectStatus(name string) *projectStatus {
+	return &projectStatus{name: name, startedAt: time.Now(), requests: make(chan string, SYNC_REQUESTS_BUFFER)}
+}
+
+// projectStatusSnapshot is a copy of a project status that's safe to read.
+type projectStatusSnapshot struct {
+	Name          string
+	Running       bool
+	Failures      int
+	LastErr       error
+	LastSuccessAt time.Time
+	GaveUp        bool
+	StartedAt     time.Time
+}
+
+func (s *projectStatus) snapshot() projectStatusSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return projectStatusSnapshot{
+		Name:          s.name,
+		Running:       s.running,
+		Failures:      s.failures,
+		LastErr:       s.lastErr,
+		LastSuccessAt: s.lastSuccessAt,
+		GaveUp:        s.gaveUp,
+		StartedAt:     s.startedAt,
+	}
+}
+
+func (s *projectStatus) setRunning(running bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = running
+}
+
+// requestSync queues an immediate sync of an item, or of the whole project
+// when itemId is empty. It fails when the project sync loop is not running.
+func (s *projectStatus) requestSync(itemId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return errProjectNotRunning
+	}
+
+	select {
+	case s.requests <- itemId:
+		return nil
+	default:
+		return errTooManySyncRequests
+	}
+}
+
+// cycleSucceeded records a successful sync cycle and resets the
+// consecutive failures count.
+func (s *projectStatus) cycleSucceeded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = 0
+	s.lastErr = nil
+	s.lastSuccessAt = time.Now()
+}
+
+// failed records a failed sync run and returns the consecutive failures count.
+func (s *projectStatus) failed(err error) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures++
+	s.lastErr = err
+	return s.failures
+}
+
+func (s *projectStatus) giveUp() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gaveUp = true
+}
+
+// Err returns the last error of the project if the project gave up syncing.
+func (s *projectStatus) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.gaveUp {
+		return nil
+	}
+	return s.lastErr
+}
+
+// waitForSync waits until the given duration passes or a sync is requested,
+// it returns the requested item id (empty for the whole project) and false
+// when a shutdown is requested.
+func (s *projectStatus) waitForSync(ctx context.Context, d time.Duration) (string, bool) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-shutdownDone(ctx):
+		return "", false
+	case itemId := <-s.requests:
+		return itemId, true
+	case <-timer.C:
+		return "", true
+	}
+}
+
+// superviseProject runs the project sync and, if it fails, retries it with
+// an exponential backoff until the error budget (consecutive failures) is
+// spent. Other projects keep running regardless of the project result.
+func superviseProject(ctx context.Context, args Cmd, config Config, projPos int, m *models.Models, ghs *githubClients, status *projectStatus, log *logrus.Logger) {
+	projectCfg := config.Projects[projPos]
+	budget := DEFAULT_ERROR_BUDGET
+	if config.ErrorBudget != nil {
+		budget = *config.ErrorBudget
+	}
+
+	for {
+		err := runProjectSync(ctx, args, config, projPos, m, ghs, status, log)
+		if err == nil || shuttingDown(ctx) {
+			return
+		}
+
+		failures := status.failed(err)
+		if failures > budget {
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "failures": failures}).Errorln("project error budget spent, giving up")
+			status.giveUp()
+			return
+		}
+
+		delay := getRetryDelay(failures)
+		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "failures": failures, "retryIn": delay.String()}).Errorln("project sync failed, retrying")
+		if !sleep(ctx, delay) {
+			return
+		}
+	}
+}
+
+// runProjectSync runs syncProject with the GitHub client of the project
+// token, turning panics into errors so a project can't take down the whole
+// process.
+func runProjectSync(ctx context.Context, args Cmd, config Config, projPos int, m *models.Models, ghs *githubClients, status *projectStatus, log *logrus.Logger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("project sync panicked: %v", r)
+		}
+	}()
+
+	gh, err := ghs.get(config.Projects[projPos].Name)
+	if err != nil {
+		return err
+	}
+
+	return syncProject(ctx, args, config, projPos, m, gh, status, log)
+}
+
+func getRetryDelay(failures int) time.Duration {
+	delay := SYNC_RETRY_BASE_DELAY
+	for i := 1; i < failures && delay < SYNC_RETRY_MAX_DELAY; i++ {
+		delay *= 2
+	}
+	return min(delay, SYNC_RETRY_MAX_DELAY)
+}
+
+// exitWithSyncSummary prints the projects that failed and exits with 0 when
+// every project succeeded, 1 when every project failed or
+// EXIT_CODE_PARTIAL_FAILURE when only some of them failed.
+func exitWithSyncSummary(statuses []*projectStatus) {
+	failed := 0
+	for _, status := range statuses {
+		if err := status.Err(); err != nil {
+			fmt.Printf("error: project \"%s\" failed: %s\n", status.name, err.Error())
+			failed++
+		}
+	}
+
+	switch {
+	case failed == 0:
+		os.Exit(0)
+	case failed == len(statuses):
+		os.Exit(1)
+	default:
+		os.Exit(EXIT_CODE_PARTIAL_FAILURE)
+	}
+}
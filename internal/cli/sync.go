@@ -5,20 +5,21 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"reflect"
 	"regexp"
 	"slices"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
 	jira "github.com/ctreminiom/go-atlassian/jira/v3"
 	jiramodels "github.com/ctreminiom/go-atlassian/pkg/infra/models"
 	"github.com/iolave/jira-tickets-from-gh/internal/github"
 	"github.com/iolave/jira-tickets-from-gh/internal/helpers"
+	"github.com/iolave/jira-tickets-from-gh/internal/metrics"
 	"github.com/iolave/jira-tickets-from-gh/internal/models"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 )
 
 // SyncCmdAction syncs GitHub projects with Jira cloud boards.
@@ -28,132 +29,192 @@ func SyncCmdAction(args Cmd) {
 		level = logrus.DebugLevel
 	}
 	log := newLogger(level)
-	log.Debugln("initializing db models")
-	m, err := models.Initialize()
 
+	if args.Sync == nil {
+		log.Errorln("call to sync action args.Sync property is nil")
+		exitOnInvalidCall("sync")
+	}
+
+	config, err := readConfig(args.Sync.Config, log)
 	if err != nil {
-		log.WithFields(logrus.Fields{"err": err}).Errorln("db models initialization failed")
 		exitFromErr(err)
 	}
 
-	if args.Sync == nil {
-		log.WithFields(logrus.Fields{"err": err}).Errorln("call to sync action args.Sync property is nil")
-		exitOnInvalidCall("sync")
+	// a dry run must not change the local storage, so it is neither created
+	// nor migrated
+	dryRun := args.Sync.DryRun != nil && *args.Sync.DryRun
+	var m *models.Models
+	if dryRun {
+		m, err = openModelsForPlan(args, args.Sync.Config, config, log)
+	} else {
+		m, err = initializeModels(args, args.Sync.Config, config, log)
+	}
+	if err != nil {
+		exitFromErr(err)
 	}
 
-	log.WithFields(logrus.Fields{"config": args.Sync.Config}).Debugln("reading config file from location")
-	b, err := os.ReadFile(args.Sync.Config)
+	ghs := newGithubClients(args)
+	for _, proj := range config.Projects {
+		if _, err := ghs.get(proj.Name); err != nil {
+			log.WithFields(logrus.Fields{"err": err, "project": proj.Name}).Errorln("getting project github token failed")
+			exitFromErr(err)
+		}
+	}
 
-	if err != nil {
-		log.WithFields(logrus.Fields{"config": args.Sync.Config, "err": err}).Errorln("reading config file from location failed")
+	// the root context is cancelled on SIGINT/SIGTERM so sleeping loops wake
+	// up at once and in-flight item operations get a grace period to finish
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if dryRun {
+		dryRunSync(ctx, args, config, m, ghs, log)
+	}
+
+	daemon := startSyncDaemon(ctx, args, config, m, ghs, log)
+
+	if config.EnableAPI != nil && *config.EnableAPI {
+		if err := startAPIServer(ctx, args, daemon.state, m, ghs, log); err != nil {
+			log.WithFields(logrus.Fields{"err": err}).Errorln("starting management api failed")
+			exitFromErr(err)
+		}
+	}
+
+	if (config.EnableMetrics != nil && *config.EnableMetrics) || (config.EnableHealth != nil && *config.EnableHealth) {
+		startMonitoringServer(ctx, args, daemon.state, ghs, log)
+	}
+
+	daemon.wait(args.Sync.Config)
+	m.Close()
+
+	if ctx.Err() != nil {
+		log.Infoln("sync stopped gracefully")
+	}
+	_, statuses := daemon.state.get()
+	exitWithSyncSummary(statuses)
+}
+
+// dryRunSync prints the plan of every project and exits with a code that
+// tells whether changes are pending.
+func dryRunSync(ctx context.Context, args Cmd, config Config, m *models.Models, ghs *githubClients, log *logrus.Logger) {
+	if args.Sync.PlanFormat != PLAN_FORMAT_TEXT && args.Sync.PlanFormat != PLAN_FORMAT_JSON {
+		err := fmt.Errorf(`"--plan-format" should be one of [%s, %s]`, PLAN_FORMAT_TEXT, PLAN_FORMAT_JSON)
 		exitFromErr(err)
 	}
 
-	log.Debugln("parsing config content")
+	plans := []ProjectPlan{}
+	pending := false
+	for i := 0; i < len(config.Projects); i++ {
+		gh, err := ghs.get(config.Projects[i].Name)
+		if err != nil {
+			exitFromErr(err)
+		}
+		plan, err := planProject(ctx, args, config, i, m, gh, log)
+		if err != nil {
+			log.WithFields(logrus.Fields{"err": err, "project": config.Projects[i].Name}).Errorln("planning project sync failed")
+			exitFromErr(err)
+		}
+		if len(plan.Actions) > 0 {
+			pending = true
+		}
+		plans = append(plans, plan)
+	}
+
+	if err := writePlans(os.Stdout, plans, args.Sync.PlanFormat); err != nil {
+		exitFromErr(err)
+	}
+
+	if pending {
+		os.Exit(EXIT_CODE_CHANGES_PENDING)
+	}
+	os.Exit(0)
+}
+
+// readConfig reads, parses and validates the config file at the given path.
+func readConfig(path string, log *logrus.Logger) (Config, error) {
 	var config Config
-	err = yaml.Unmarshal(b, &config)
+
+	log.WithFields(logrus.Fields{"config": path}).Debugln("reading config file and its includes from location")
+	files, err := readConfigFiles(path)
+	if err != nil {
+		log.WithFields(logrus.Fields{"config": path, "err": err}).Errorln("reading config file and its includes from location failed")
+		return config, err
+	}
+
+	log.WithFields(logrus.Fields{"files": len(files)}).Debugln("merging included projects and defaults into config")
+	root, err := mergeConfigFiles(files)
+	if err != nil {
+		log.WithFields(logrus.Fields{"err": err}).Errorln("merging included projects and defaults into config failed")
+		return config, err
+	}
+
+	log.Debugln("parsing config content")
+	err = root.Decode(&config)
 	if err != nil {
 		log.WithFields(logrus.Fields{"err": err}).Errorln("parsing config content failed")
-		exitFromErr(err)
+		return config, err
 	}
 
 	log.Debugln("validating config properties")
 	err = config.validate()
 	if err != nil {
 		log.WithFields(logrus.Fields{"err": err}).Errorln("config properties validation failed")
-		exitFromErr(err)
-	}
-
-	if args.GithubToken == nil {
-		err := errors.New(`please set the "GITHUB_TOKEN" env variable`)
-		log.WithFields(logrus.Fields{"err": err}).Errorln("GithubToken property is nil")
-		exitFromErr(err)
+		return config, err
 	}
-	gh := github.New(*args.GithubToken)
 
-	var wg sync.WaitGroup
-	for i := 0; i < len(config.Projects); i++ {
-		// Increment the wait group counter
-		wg.Add(1)
-		go func() {
-			// Decrement the counter when the go routine completes
-			defer wg.Done()
-			syncProject(args, config, i, m, gh, log)
-		}()
-	}
-	wg.Wait()
+	return config, nil
 }
 
-func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *github.GitHubClient, log *logrus.Logger) {
+// syncProject syncs a project until its loop ends or an error that prevents
+// the project from being synced is found, per item errors are logged and
+// skipped.
+func syncProject(ctx context.Context, args Cmd, config Config, projPos int, m *models.Models, gh *github.GitHubClient, status *projectStatus, log *logrus.Logger) (err error) {
 	projectCfg := config.Projects[projPos]
 	log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("syncing project")
 
-	// creates new jira client
-	log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("creating new jira client")
-	url := fmt.Sprintf("https://%s.atlassian.net", projectCfg.Jira.Subdomain)
-	jc, err := jira.New(nil, url)
-	if err != nil {
-		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("failed creating jira client")
-		exitFromErr(err)
-	}
-	log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("getting jira creds")
-	token, email, err := getProjectJiraCreds(args, projectCfg.Name)
-	if err != nil {
-		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("failed to retrieve jira creds")
-		exitFromErr(err)
-	}
-	jc.Auth.SetBasicAuth(email, token)
+	// ctx is only cancelled once the grace period passes after a shutdown
+	// request, so the in-flight item operation can finish
+	ctx, cancel := withGracePeriod(ctx, SHUTDOWN_GRACE_PERIOD)
+	defer cancel()
+	// requests sent with ctx are labeled with the project in metrics
+	ctx = metrics.WithProject(ctx, projectCfg.Name)
 
-	// get and set required github project fields into the model
-	log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("retrieving github project fields")
-	fieldsResult, _, err := gh.GetProjectFields(projectCfg.Github.ProjectID)
+	schedule, err := newProjectSchedule(config, projPos)
 	if err != nil {
-		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("failed retrieving github project fields")
-		exitFromErr(err)
+		return err
 	}
-	fieldsIds := struct{ JiraUrl, JiraIssueType, Title, Estimate, Status, Repo, Assignees string }{}
-	for _, v := range fieldsResult.Data.Node.Fields.Nodes {
-		switch v.Name {
-		case models.FIELD_NAME_JIRA_URL:
-			fieldsIds.JiraUrl = v.ID
-		case models.FIELD_NAME_JIRA_ISSUE_TYPE:
-			fieldsIds.JiraIssueType = v.ID
-		case models.FIELD_NAME_TITLE:
-			fieldsIds.Title = v.ID
-		case models.FIELD_NAME_ESTIMATE:
-			fieldsIds.Estimate = v.ID
-		case models.FIELD_NAME_STATUS:
-			fieldsIds.Status = v.ID
-		case models.FIELD_NAME_ASSIGNEES:
-			fieldsIds.Assignees = v.ID
-		case models.FIELD_NAME_REPO:
-			fieldsIds.Repo = v.ID
+
+	// the first cycle also waits for the active windows, syncs requested
+	// through the api start it right away
+	if schedule != nil {
+		if start := schedule.NextActive(time.Now()); start.After(time.Now()) {
+			log.WithFields(logrus.Fields{"nextRun": start, "project": projectCfg.Name}).Infoln("outside of the active windows, sleeping")
+			if _, ok := status.waitForSync(ctx, time.Until(start)); !ok {
+				log.WithFields(logrus.Fields{"project": projectCfg.Name}).Infoln("shutdown requested, stopping")
+				return nil
+			}
 		}
 	}
-	v := reflect.ValueOf(fieldsIds)
-	for i := 0; i < v.NumField(); i++ {
-		if v.Field(i).String() == "" {
-			err := fmt.Errorf(`error: missing field in project "%s", make sure it have the following fields [%s, %s, %s, %s, %s, %s, %s]`,
-				projectCfg.Name,
-				models.FIELD_NAME_JIRA_URL,
-				models.FIELD_NAME_JIRA_ISSUE_TYPE,
-				models.FIELD_NAME_TITLE,
-				models.FIELD_NAME_ESTIMATE,
-				models.FIELD_NAME_STATUS,
-				models.FIELD_NAME_ASSIGNEES,
-				models.FIELD_NAME_REPO,
-			)
-			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("some fields are not present in github project")
-			exitFromErr(err)
+
+	cycleStart := time.Now()
+	defer func() {
+		if err != nil {
+			metrics.CycleFailed(projectCfg.Name, time.Since(cycleStart))
 		}
+	}()
 
+	// creates new jira client
+	log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("creating new jira client")
+	jc, err := newProjectJiraClient(args, config, projPos)
+	if err != nil {
+		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("failed creating jira client")
+		return err
 	}
-	// TODO: maybe is not necesesary to store the project fields id as they can be accessed from variables
-	log.WithFields(logrus.Fields{"project": projectCfg.Name, "fields": fieldsIds}).Debugln("upserting project fields ids")
-	p, err := m.Projects.Upsert(projectCfg.Github.ProjectID, fieldsIds.JiraUrl, fieldsIds.JiraIssueType, fieldsIds.Title, fieldsIds.Estimate, fieldsIds.Status, fieldsIds.Assignees, fieldsIds.Repo)
+
+	// get and set required github project fields into the model
+	log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("retrieving github project fields")
+	p, err := upsertProjectFields(ctx, config, projPos, m, gh, log)
 	if err != nil {
-		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "fields": fieldsIds}).Errorln("upserting project fields ids failed")
-		exitFromErr(err)
+		return err
 	}
 
 	// translates github users to jira account ids
@@ -162,12 +223,11 @@ func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *git
 
 	for i := 0; i < len(projectCfg.Assignees); i++ {
 		email := projectCfg.Assignees[i].JiraEmail
-		// FIXME: response returns a 404 when credentials are invalid, fix this
-		users, _, err := jc.User.Search.Do(context.Background(), "", email, 0, 2)
-
+		users, _, err := jc.User.Search.Do(ctx, "", email, 0, 2)
 		if err != nil {
+			err = explainJiraNotFound(ctx, jc, err)
 			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "assignee": projectCfg.Assignees[i].JiraEmail}).Errorln("translating jira emails to github user failed")
-			exitFromErr(err)
+			return err
 		}
 
 		if len(users) != 1 {
@@ -177,59 +237,63 @@ func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *git
 		assigneesMap[projectCfg.Assignees[i].GHUser] = users[0].AccountID
 	}
 
+	log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("resuming pending operations")
+	if err := resumePendingOperations(ctx, config, projPos, jc, gh, *p, assigneesMap, log); err != nil {
+		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("resuming pending operations failed")
+		return err
+	}
+
 	log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("querying local issues")
 	issues, err := p.GetAllIssues()
 	if err != nil {
 		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("querying local issues failed")
-		exitFromErr(err)
+		return err
 	}
 	if len(issues) == 0 {
-		var remoteIssues []models.RemoteIssue
 		log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("querying gh remote issues")
-		remoteIssuesResult, _, err := gh.GetProjectItems(p.ID, getGHFields())
+		remoteIssues, _, err := fetchRemoteIssues(ctx, gh, p.ID)
 		if err != nil {
 			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("querying gh remote issues failed")
-			exitFromErr(err)
+			return err
 		}
+		metrics.ItemsSeen(projectCfg.Name, len(remoteIssues))
+		recordIssuesStatus(*p, remoteIssues, log)
 
 		log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("upserting remote issues")
-		remoteIssuesResult.UnmarshallItems(&remoteIssues)
-		remoteIssues = helpers.FilterSlice(remoteIssues, func(ri models.RemoteIssue) bool {
-			return ri.Status != nil && ri.JiraIssueType != nil
-		})
-		remoteIssues = helpers.MapSlice(remoteIssues, func(ri models.RemoteIssue) models.RemoteIssue {
-			if ri.JiraUrl.Text != nil {
-				match, _ := regexp.MatchString(`^https\:\/\/[a-zA-Z0-9]*\.atlassian\.net\/browse\/.*`, *ri.JiraUrl.Text)
-				if !match {
-					ri.JiraUrl.Text = nil
-				}
-			}
-			return ri
-		})
+		remoteIssues = filterSyncableIssues(remoteIssues)
 		_, err = p.UpsertManyIssues(remoteIssues)
 		if err != nil {
 			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("upserting remote issues failed")
-			exitFromErr(err)
+			return err
 		}
 
 		log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("querying local issues with jira url")
 		issues, err := p.GetIssuesWithUrl()
 		if err != nil {
 			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("querying local issues with jira url failed")
-			exitFromErr(err)
+			return err
 		}
 		for _, is := range issues {
-			updateJiraIssueFromGhIssueWithUrl(config, projPos, jc, *is)
+			if shuttingDown(ctx) {
+				return nil
+			}
+			if err := updateJiraIssueFromGhIssueWithUrl(ctx, config, projPos, jc, *p, *is); err != nil {
+				log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": is.GitHubID}).Errorln("transitioning jira issue failed")
+			}
 		}
 
 		log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("querying local issues without jira url")
 		issues, err = p.GetIssuesWithoutUrl()
 		if err != nil {
 			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("querying local issues without jira url failed")
-			exitFromErr(err)
+			return err
 		}
 		for _, is := range issues {
+			if shuttingDown(ctx) {
+				return nil
+			}
 			if err = createJiraIssueFromGhIssueWithoutUrl(
+				ctx,
 				config,
 				projPos,
 				jc,
@@ -238,42 +302,85 @@ func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *git
 				*is,
 				assigneesMap,
 			); err != nil {
-				exitFromErr(err)
+				log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": is.GitHubID}).Errorln("creating jira issue failed")
 			}
 
 		}
 	}
 
-	for config.SleepTime != nil && *config.SleepTime >= 0 {
-		log.WithFields(logrus.Fields{"sleepTime": *config.SleepTime, "project": projectCfg.Name}).Infoln("sleeping")
-		time.Sleep(time.Duration(*config.SleepTime) * time.Millisecond)
+	if args.Sync.Prune != nil && *args.Sync.Prune {
+		log.WithFields(logrus.Fields{"project": projectCfg.Name}).Infoln("pruning issues removed from github project")
+		remoteIssues, remoteIssuesResult, err := fetchRemoteIssues(ctx, gh, p.ID)
+		if err != nil {
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("querying gh remote issues failed")
+			return err
+		}
+		if remoteIssuesResult.Errors != nil {
+			err := github.GetErrorFromErrors(remoteIssuesResult.Errors)
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("querying gh remote issues returned errors, refusing to prune")
+			return err
+		}
+		if err := pruneRemovedIssues(ctx, config, projPos, jc, *p, remoteIssues, log); err != nil {
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("pruning issues removed from github project failed")
+			return err
+		}
+	}
+
+	status.cycleSucceeded()
+	metrics.CycleSucceeded(projectCfg.Name, time.Since(cycleStart))
+
+	if schedule != nil {
+		status.setRunning(true)
+		defer status.setRunning(false)
+	}
+
+	for schedule != nil {
+		nextRun := schedule.Next(time.Now())
+		log.WithFields(logrus.Fields{"nextRun": nextRun, "project": projectCfg.Name}).Infoln("sleeping")
+		// syncs requested through the api wake the loop up before the next
+		// run, an item id restricts the cycle to that item
+		itemId, ok := status.waitForSync(ctx, time.Until(nextRun))
+		if !ok {
+			log.WithFields(logrus.Fields{"project": projectCfg.Name}).Infoln("shutdown requested, stopping")
+			return nil
+		}
+		if itemId != "" {
+			log.WithFields(logrus.Fields{"project": projectCfg.Name, "itemId": itemId}).Infoln("item sync requested")
+		}
+		cycleStart = time.Now()
+
+		log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("resuming pending operations")
+		if err := resumePendingOperations(ctx, config, projPos, jc, gh, *p, assigneesMap, log); err != nil {
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("resuming pending operations failed")
+		}
 
 		log.WithFields(logrus.Fields{"project": projectCfg.Name}).Infoln("refreshing remote github issues")
-		remoteIssuesResult, _, err := gh.GetProjectItems(p.ID, getGHFields())
-		var remoteIssues []models.RemoteIssue
-		remoteIssuesResult.UnmarshallItems(&remoteIssues)
-		remoteIssues = helpers.FilterSlice(remoteIssues, func(ri models.RemoteIssue) bool {
-			return ri.Status != nil && ri.JiraIssueType != nil
-		})
-		remoteIssues = helpers.MapSlice(remoteIssues, func(ri models.RemoteIssue) models.RemoteIssue {
-			if ri.JiraUrl.Text != nil {
-				match, _ := regexp.MatchString(`^https\:\/\/[a-zA-Z0-9]*\.atlassian\.net\/browse\/.*`, *ri.JiraUrl.Text)
-				if !match {
-					ri.JiraUrl.Text = nil
-				}
-			}
-			return ri
-		})
+		allRemoteIssues, remoteIssuesResult, err := fetchRemoteIssues(ctx, gh, p.ID)
 		if err != nil {
 			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("refreshing remote github issues fields")
+			metrics.CycleFailed(projectCfg.Name, time.Since(cycleStart))
 			continue
 		}
+		remoteIssues := filterSyncableIssues(allRemoteIssues)
+		if itemId == "" {
+			metrics.ItemsSeen(projectCfg.Name, len(allRemoteIssues))
+			recordIssuesStatus(*p, allRemoteIssues, log)
+		}
+		if itemId != "" {
+			remoteIssues = helpers.FilterSlice(remoteIssues, func(ri models.RemoteIssue) bool {
+				return ri.ID == itemId
+			})
+		}
 
 		riWithoutUrl := helpers.FilterSlice(remoteIssues, func(ri models.RemoteIssue) bool {
 			return ri.JiraUrl.Text == nil
 		})
 		for _, ri := range riWithoutUrl {
-			createJiraIssueFromGhIssueWithoutUrl(
+			if shuttingDown(ctx) {
+				return nil
+			}
+			if err := createJiraIssueFromGhIssueWithoutUrl(
+				ctx,
 				config,
 				projPos,
 				jc,
@@ -281,7 +388,9 @@ func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *git
 				*p,
 				*ri.ToIssue(p.ID),
 				assigneesMap,
-			)
+			); err != nil {
+				log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": ri.ID}).Errorln("creating jira issue failed")
+			}
 		}
 
 		log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("obtaining local issues diff")
@@ -291,12 +400,16 @@ func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *git
 		diffs, err := p.GetIssuesDiff(riWithUrl)
 		if err != nil {
 			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("obtaining local issues diff failed")
-			exitFromErr(err)
+			return err
 		}
 
 		for _, issueDiff := range diffs {
+			if shuttingDown(ctx) {
+				return nil
+			}
 			if issueDiff.Issue.JiraURL == nil {
-				createJiraIssueFromGhIssueWithoutUrl(
+				if err := createJiraIssueFromGhIssueWithoutUrl(
+					ctx,
 					config,
 					projPos,
 					jc,
@@ -304,12 +417,15 @@ func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *git
 					*p,
 					*issueDiff.Issue,
 					assigneesMap,
-				)
+				); err != nil {
+					log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": issueDiff.Issue.GitHubID}).Errorln("creating jira issue failed")
+				}
 				continue
 			}
 			urlSplitted := strings.Split(*issueDiff.Issue.JiraURL, "/")
 			if len(urlSplitted) == 0 {
-				createJiraIssueFromGhIssueWithoutUrl(
+				if err := createJiraIssueFromGhIssueWithoutUrl(
+					ctx,
 					config,
 					projPos,
 					jc,
@@ -317,68 +433,15 @@ func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *git
 					*p,
 					*issueDiff.Issue,
 					assigneesMap,
-				)
+				); err != nil {
+					log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": issueDiff.Issue.GitHubID}).Errorln("creating jira issue failed")
+				}
 				continue
 			}
 			issueKey := urlSplitted[len(urlSplitted)-1]
 
-			switch *issueDiff.PrevStatus {
-			case models.STATUS_TODO:
-				switch issueDiff.NewStatus {
-				case models.STATUS_WIP:
-					// TODO: this should return an error
-					transitionToWip(jc, issueKey, projPos, config, *issueDiff.Issue)
-
-					if _, err := p.UpsertIssue(
-						issueDiff.Issue.GitHubID,
-						issueDiff.Issue.Title,
-						issueDiff.Issue.Status,
-						issueDiff.Issue.JiraURL,
-						issueDiff.Issue.JiraIssueType,
-						issueDiff.Issue.Repository,
-						issueDiff.Issue.Estimate,
-						&issueDiff.Issue.Assignees,
-					); err != nil {
-						log.WithFields(logrus.Fields{"err": err, "projectId": p.ID}).Errorln("failed to upsert issue")
-					}
-				case models.STATUS_DONE:
-					// TODO: this should return an error
-					transitionToWip(jc, issueKey, projPos, config, *issueDiff.Issue)
-					// TODO: this should return an error
-					transitionToDone(jc, issueKey, projPos, config, *issueDiff.Issue)
-
-					if _, err := p.UpsertIssue(
-						issueDiff.Issue.GitHubID,
-						issueDiff.Issue.Title,
-						issueDiff.Issue.Status,
-						issueDiff.Issue.JiraURL,
-						issueDiff.Issue.JiraIssueType,
-						issueDiff.Issue.Repository,
-						issueDiff.Issue.Estimate,
-						&issueDiff.Issue.Assignees,
-					); err != nil {
-						log.WithFields(logrus.Fields{"err": err, "projectId": p.ID}).Errorln("failed to upsert issue")
-					}
-				}
-			case models.STATUS_WIP:
-				switch issueDiff.NewStatus {
-				case models.STATUS_DONE:
-					// TODO: this should return an error
-					transitionToDone(jc, issueKey, projPos, config, *issueDiff.Issue)
-
-					if _, err := p.UpsertIssue(
-						issueDiff.Issue.GitHubID,
-						issueDiff.Issue.Title,
-						issueDiff.Issue.Status,
-						issueDiff.Issue.JiraURL,
-						issueDiff.Issue.JiraIssueType,
-						issueDiff.Issue.Repository,
-						issueDiff.Issue.Estimate,
-						&issueDiff.Issue.Assignees,
-					); err != nil {
-						log.WithFields(logrus.Fields{"err": err, "projectId": p.ID}).Errorln("failed to upsert issue")
-					}
-				}
+			if err := transitionJiraIssueFromDiff(ctx, config, projPos, jc, *p, issueKey, issueDiff); err != nil {
+				log.WithFields(logrus.Fields{"err": err, "projectId": p.ID}).Errorln("failed to transition issue")
 			}
 		}
 
@@ -390,7 +453,7 @@ func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *git
 		idsThatdoesntExist, err := p.FindIssuesThatDoesntExist(ids)
 		if err != nil {
 			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("obtaining new issues failed")
-			exitFromErr(err)
+			return err
 		}
 		newIssues := helpers.FilterSlice(remoteIssues, func(i models.RemoteIssue) bool {
 			idx := slices.IndexFunc(idsThatdoesntExist, func(id string) bool { return id == i.ID })
@@ -400,7 +463,33 @@ func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *git
 			return true
 		})
 		for _, newIssue := range newIssues {
-			createJiraIssueFromGhIssueWithoutUrl(
+			if shuttingDown(ctx) {
+				return nil
+			}
+			// items that already have a jira url (i.e. restored items that
+			// were previously removed) are adopted instead of re-created
+			if is := newIssue.ToIssue(p.ID); is.JiraURL != nil {
+				log.WithFields(logrus.Fields{"project": projectCfg.Name, "itemId": is.GitHubID, "jiraUrl": *is.JiraURL}).Infoln("restoring issue that already has a jira url")
+				if _, err := p.UpsertIssue(
+					is.GitHubID,
+					is.Title,
+					is.Status,
+					is.JiraURL,
+					is.JiraIssueType,
+					is.Repository,
+					is.Estimate,
+					&is.Assignees,
+				); err != nil {
+					log.WithFields(logrus.Fields{"err": err, "projectId": p.ID}).Errorln("failed to upsert issue")
+					continue
+				}
+				if err := p.DeleteTombstone(is.GitHubID); err != nil {
+					log.WithFields(logrus.Fields{"err": err, "projectId": p.ID}).Errorln("failed to delete issue tombstone")
+				}
+				continue
+			}
+			if err := createJiraIssueFromGhIssueWithoutUrl(
+				ctx,
 				config,
 				projPos,
 				jc,
@@ -408,20 +497,144 @@ func syncProject(args Cmd, config Config, projPos int, m *models.Models, gh *git
 				*p,
 				*newIssue.ToIssue(p.ID),
 				assigneesMap,
+			); err != nil {
+				log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": newIssue.ID}).Errorln("creating jira issue failed")
+			}
+		}
+
+		if itemId != "" {
+			continue
+		}
+
+		if remoteIssuesResult.Errors != nil {
+			err := github.GetErrorFromErrors(remoteIssuesResult.Errors)
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Warnln("skipping removed issues detection as gh remote issues query returned errors")
+		} else {
+			log.WithFields(logrus.Fields{"project": projectCfg.Name}).Debugln("looking for issues removed from github project")
+			if err := pruneRemovedIssues(ctx, config, projPos, jc, *p, allRemoteIssues, log); err != nil {
+				log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("looking for issues removed from github project failed")
+			}
+		}
+
+		status.cycleSucceeded()
+		metrics.CycleSucceeded(projectCfg.Name, time.Since(cycleStart))
+	}
+
+	return nil
+}
+
+type projectFieldsIds struct{ JiraUrl, JiraIssueType, Title, Estimate, Status, Repo, Assignees string }
+
+// getProjectFieldsIds retrieves the required GitHub project fields ids,
+// failing if any of them is not present in the project.
+func getProjectFieldsIds(ctx context.Context, config Config, projPos int, gh *github.GitHubClient, log *logrus.Logger) (projectFieldsIds, error) {
+	projectCfg := config.Projects[projPos]
+	fieldsIds := projectFieldsIds{}
+
+	fieldsResult, _, err := gh.GetProjectFields(ctx, projectCfg.Github.ProjectID)
+	if err != nil {
+		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("failed retrieving github project fields")
+		return fieldsIds, err
+	}
+	for _, v := range fieldsResult.Data.Node.Fields.Nodes {
+		switch v.Name {
+		case models.FIELD_NAME_JIRA_URL:
+			fieldsIds.JiraUrl = v.ID
+		case models.FIELD_NAME_JIRA_ISSUE_TYPE:
+			fieldsIds.JiraIssueType = v.ID
+		case models.FIELD_NAME_TITLE:
+			fieldsIds.Title = v.ID
+		case models.FIELD_NAME_ESTIMATE:
+			fieldsIds.Estimate = v.ID
+		case models.FIELD_NAME_STATUS:
+			fieldsIds.Status = v.ID
+		case models.FIELD_NAME_ASSIGNEES:
+			fieldsIds.Assignees = v.ID
+		case models.FIELD_NAME_REPO:
+			fieldsIds.Repo = v.ID
+		}
+	}
+	v := reflect.ValueOf(fieldsIds)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).String() == "" {
+			err := fmt.Errorf(`error: missing field in project "%s", make sure it have the following fields [%s, %s, %s, %s, %s, %s, %s]`,
+				projectCfg.Name,
+				models.FIELD_NAME_JIRA_URL,
+				models.FIELD_NAME_JIRA_ISSUE_TYPE,
+				models.FIELD_NAME_TITLE,
+				models.FIELD_NAME_ESTIMATE,
+				models.FIELD_NAME_STATUS,
+				models.FIELD_NAME_ASSIGNEES,
+				models.FIELD_NAME_REPO,
 			)
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name}).Errorln("some fields are not present in github project")
+			return fieldsIds, err
 		}
+
+	}
+
+	return fieldsIds, nil
+}
+
+// upsertProjectFields retrieves the required GitHub project fields ids and
+// stores them into the model.
+func upsertProjectFields(ctx context.Context, config Config, projPos int, m *models.Models, gh *github.GitHubClient, log *logrus.Logger) (*models.Project, error) {
+	projectCfg := config.Projects[projPos]
+
+	fieldsIds, err := getProjectFieldsIds(ctx, config, projPos, gh, log)
+	if err != nil {
+		return nil, err
+	}
+	// TODO: maybe is not necesesary to store the project fields id as they can be accessed from variables
+	log.WithFields(logrus.Fields{"project": projectCfg.Name, "fields": fieldsIds}).Debugln("upserting project fields ids")
+	p, err := m.Projects.Upsert(projectCfg.Github.ProjectID, fieldsIds.JiraUrl, fieldsIds.JiraIssueType, fieldsIds.Title, fieldsIds.Estimate, fieldsIds.Status, fieldsIds.Assignees, fieldsIds.Repo)
+	if err != nil {
+		log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "fields": fieldsIds}).Errorln("upserting project fields ids failed")
+		return nil, err
 	}
+
+	return p, nil
 }
 
 type SyncCmd struct {
-	Config string `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Config     string `arg:"required,--config,-c" help:"path to config file" placeholder:"<PATH>"`
+	Prune      *bool  `arg:"--prune" help:"reconcile stored issues whose github item was removed or archived before syncing"`
+	DryRun     *bool  `arg:"--dry-run" help:"print the changes the sync would do without doing them (exits with code 2 when changes are pending)"`
+	PlanFormat string `arg:"--plan-format" default:"text" help:"dry-run plan output format [text, json]" placeholder:"<FORMAT>"`
 }
 
+// create and transition operations steps, the transitions run within the
+// "_TO_WIP" and "_TO_DONE" ones are recorded as "<step>:<transition id>"
+const (
+	OPERATION_STEP_JIRA_CREATED              string = "jira_created"
+	OPERATION_STEP_JIRA_STAMPED              string = "jira_stamped"
+	OPERATION_STEP_GITHUB_WRITTEN            string = "github_written"
+	OPERATION_STEP_STORED                    string = "stored"
+	OPERATION_STEP_JIRA_TRANSITIONED         string = "jira_transitioned"
+	OPERATION_STEP_JIRA_TRANSITIONED_TO_WIP  string = "jira_transitioned_to_wip"
+	OPERATION_STEP_JIRA_TRANSITIONED_TO_DONE string = "jira_transitioned_to_done"
+)
+
+const (
+	REMOVED_ACTION_NONE       string = "none"
+	REMOVED_ACTION_TRANSITION string = "transition"
+	REMOVED_ACTION_LABEL      string = "label"
+	REMOVED_ACTION_COMMENT    string = "comment"
+)
+
 type Config struct {
-	SleepTime *int  `yaml:"sleepTime"`
-	EnableAPI *bool `yaml:"enableApi"`
-	Projects  []struct {
-		Name      string `yaml:"name"`
+	SleepTime       *int            `yaml:"sleepTime"`
+	EnableAPI       *bool           `yaml:"enableApi"`
+	APIAddress      *string         `yaml:"apiAddress"`
+	EnableMetrics   *bool           `yaml:"enableMetrics"`
+	EnableHealth    *bool           `yaml:"enableHealth"`
+	MetricsAddress  *string         `yaml:"metricsAddress"`
+	ReadinessFactor *int            `yaml:"readinessFactor"`
+	ErrorBudget     *int            `yaml:"errorBudget"`
+	Database        *DatabaseConfig `yaml:"database"`
+	Projects        []struct {
+		Name      string          `yaml:"name"`
+		Schedule  *ScheduleConfig `yaml:"schedule"`
 		Assignees []struct {
 			JiraEmail string `yaml:"jiraEmail"`
 			GHUser    string `yaml:"ghUser"`
@@ -430,20 +643,40 @@ type Config struct {
 			ProjectID string `yaml:"projectId"`
 		}
 		Jira struct {
-			Subdomain     string  `yaml:"subdomain"`
-			ProjectKey    string  `yaml:"projectKey"`
-			EstimateField *string `yaml:"estimateField"`
-			IssuePrefix   *string `yaml:"issuePrefix"`
-			Issues        []struct {
-				Type              string `yaml:"type"`
-				TransitionsToWIP  []int  `yaml:"transitionsToWip"`
-				TransitionsToDone []int  `yaml:"transitionsToDone"`
-			} `yaml:"issues"`
+			Subdomain     string            `yaml:"subdomain"`
+			ProjectKey    string            `yaml:"projectKey"`
+			EstimateField *string           `yaml:"estimateField"`
+			IssuePrefix   *string           `yaml:"issuePrefix"`
+			Issues        []IssueTypeConfig `yaml:"issues"`
+			OnRemoved     struct {
+				Action      string `yaml:"action"`
+				Transitions []int  `yaml:"transitions"`
+				Label       string `yaml:"label"`
+				Comment     string `yaml:"comment"`
+			} `yaml:"onRemoved"`
 		} `yaml:"jira"`
 	} `yaml:"sync"`
 }
 
+type IssueTypeConfig struct {
+	Type              string `yaml:"type"`
+	TransitionsToWIP  []int  `yaml:"transitionsToWip"`
+	TransitionsToDone []int  `yaml:"transitionsToDone"`
+}
+
 func (c Config) validate() error {
+	if c.ErrorBudget != nil && *c.ErrorBudget < 0 {
+		return errors.New(`"errorBudget" property should be greater or equal than 0`)
+	}
+	if c.ReadinessFactor != nil && *c.ReadinessFactor < 1 {
+		return errors.New(`"readinessFactor" property should be greater or equal than 1`)
+	}
+	if c.Database != nil {
+		if err := c.Database.validate(); err != nil {
+			return err
+		}
+	}
+
 	for i := 0; i < len(c.Projects); i++ {
 		proj := c.Projects[i]
 
@@ -501,6 +734,35 @@ func (c Config) validate() error {
 			}
 		}
 
+		if _, err := newProjectSchedule(c, i); err != nil {
+			return err
+		}
+
+		onRemoved := proj.Jira.OnRemoved
+		switch onRemoved.Action {
+		case "", REMOVED_ACTION_NONE:
+		case REMOVED_ACTION_TRANSITION:
+			if len(onRemoved.Transitions) == 0 {
+				return fmt.Errorf(`"sync[%d].jira.onRemoved.transitions" property is missing`, i)
+			}
+		case REMOVED_ACTION_LABEL:
+			if onRemoved.Label == "" {
+				return fmt.Errorf(`"sync[%d].jira.onRemoved.label" property is missing`, i)
+			}
+		case REMOVED_ACTION_COMMENT:
+			if onRemoved.Comment == "" {
+				return fmt.Errorf(`"sync[%d].jira.onRemoved.comment" property is missing`, i)
+			}
+		default:
+			return fmt.Errorf(`"sync[%d].jira.onRemoved.action" property should be one of [%s, %s, %s, %s]`,
+				i,
+				REMOVED_ACTION_NONE,
+				REMOVED_ACTION_TRANSITION,
+				REMOVED_ACTION_LABEL,
+				REMOVED_ACTION_COMMENT,
+			)
+		}
+
 	}
 
 	return nil
@@ -510,8 +772,12 @@ func getProjectJiraCreds(args Cmd, projectName string) (token string, email stri
 	envEmail := fmt.Sprintf("JIRA_EMAIL_%s", projectName)
 	envToken := fmt.Sprintf("JIRA_TOKEN_%s", projectName)
 
-	email = os.Getenv(envEmail)
-	token = os.Getenv(envToken)
+	if email, _, err = lookupEnv(envEmail); err != nil {
+		return "", "", err
+	}
+	if token, _, err = lookupEnv(envToken); err != nil {
+		return "", "", err
+	}
 
 	if email != "" && token != "" {
 		return token, email, nil
@@ -542,10 +808,161 @@ func getGHFields() []github.ProjectField {
 	}
 }
 
+// fetchRemoteIssues retrieves the GitHub project items, leaving archived
+// items out and discarding jira urls that are not valid.
+func fetchRemoteIssues(ctx context.Context, gh *github.GitHubClient, projectId string) ([]models.RemoteIssue, github.GetProjectItemsResult, error) {
+	var remoteIssues []models.RemoteIssue
+	remoteIssuesResult, _, err := gh.GetProjectItems(ctx, projectId, getGHFields())
+	if err != nil {
+		return nil, remoteIssuesResult, err
+	}
+
+	remoteIssuesResult.UnmarshallItems(&remoteIssues)
+	remoteIssues = helpers.FilterSlice(remoteIssues, func(ri models.RemoteIssue) bool {
+		return !ri.IsArchived
+	})
+	remoteIssues = helpers.MapSlice(remoteIssues, func(ri models.RemoteIssue) models.RemoteIssue {
+		if ri.JiraUrl.Text != nil {
+			match, _ := regexp.MatchString(`^https\:\/\/[a-zA-Z0-9]*\.atlassian\.net\/browse\/.*`, *ri.JiraUrl.Text)
+			if !match {
+				ri.JiraUrl.Text = nil
+			}
+		}
+		return ri
+	})
+
+	return remoteIssues, remoteIssuesResult, nil
+}
+
+// recordIssuesStatus records the status changes of the GitHub project items
+// in the issues history, failing to do so doesn't stop the sync.
+func recordIssuesStatus(p models.Project, remoteIssues []models.RemoteIssue, log *logrus.Logger) {
+	recorded, err := p.RecordIssuesStatus(remoteIssues)
+	if err != nil {
+		log.WithFields(logrus.Fields{"err": err, "projectId": p.ID}).Errorln("recording issues status history failed")
+		return
+	}
+	log.WithFields(logrus.Fields{"projectId": p.ID, "changes": recorded}).Debugln("recorded issues status history")
+}
+
+// filterSyncableIssues leaves out the items that lack of a status or
+// an issue type, as they are not meant to be synced.
+func filterSyncableIssues(remoteIssues []models.RemoteIssue) []models.RemoteIssue {
+	return helpers.FilterSlice(remoteIssues, func(ri models.RemoteIssue) bool {
+		return ri.Status != nil && ri.JiraIssueType != nil
+	})
+}
+
+// getJiraIssueKey extracts the jira issue key from a jira url, if the url
+// is nil or not valid an empty string is returned.
+func getJiraIssueKey(url *string) string {
+	if url == nil {
+		return ""
+	}
+	match, _ := regexp.MatchString(`^https\:\/\/[a-zA-Z0-9]*\.atlassian\.net\/browse\/.*`, *url)
+	if !match {
+		return ""
+	}
+	urlSplitted := strings.Split(*url, "/")
+	return urlSplitted[len(urlSplitted)-1]
+}
+
+// isPruningRemovedIssues tells whether a sync of the project reconciles the
+// items removed from the GitHub project, which scheduled projects do on every
+// loop cycle and "--prune" does right away.
+func isPruningRemovedIssues(args Cmd, config Config, projPos int) (bool, error) {
+	if args.Sync.Prune != nil && *args.Sync.Prune {
+		return true, nil
+	}
+	schedule, err := newProjectSchedule(config, projPos)
+	return schedule != nil, err
+}
+
+// pruneRemovedIssues looks for stored issues whose item is no longer part of
+// the GitHub project (removed or archived), runs the configured "onRemoved"
+// action against their jira issue and records a tombstone for them.
+func pruneRemovedIssues(ctx context.Context, config Config, projPos int, jc *jira.Client, p models.Project, remoteIssues []models.RemoteIssue, log *logrus.Logger) error {
+	projectCfg := config.Projects[projPos]
+
+	ids := []string{}
+	for _, ri := range remoteIssues {
+		ids = append(ids, ri.ID)
+	}
+	removedIssues, err := p.GetIssuesNotIn(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, is := range removedIssues {
+		if shuttingDown(ctx) {
+			return nil
+		}
+		action := REMOVED_ACTION_NONE
+		key := getJiraIssueKey(is.JiraURL)
+		log.WithFields(logrus.Fields{"project": projectCfg.Name, "itemId": is.GitHubID, "jiraKey": key}).Infoln("found issue removed from github project")
+
+		if key != "" && projectCfg.Jira.OnRemoved.Action != "" {
+			action = projectCfg.Jira.OnRemoved.Action
+			if err := applyRemovedAction(ctx, jc, p, is.GitHubID, key, config, projPos); err != nil {
+				log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": is.GitHubID, "jiraKey": key, "action": action}).Errorln("removed issue action failed")
+				continue
+			}
+		}
+
+		if _, err := p.TombstoneIssue(*is, action); err != nil {
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "itemId": is.GitHubID}).Errorln("recording removed issue tombstone failed")
+		}
+	}
+
+	return nil
+}
+
+// applyRemovedAction runs the configured "onRemoved" action against a jira issue.
+func applyRemovedAction(ctx context.Context, jc *jira.Client, p models.Project, itemId, key string, config Config, projPos int) error {
+	onRemoved := config.Projects[projPos].Jira.OnRemoved
+
+	switch onRemoved.Action {
+	case REMOVED_ACTION_TRANSITION:
+		for _, t := range onRemoved.Transitions {
+			_, err := jc.Issue.Move(ctx, key, fmt.Sprintf("%d", t), nil)
+			metrics.Transitioned(config.Projects[projPos].Name, err)
+			recordAudit(p, itemId, key, models.AUDIT_ACTION_JIRA_TRANSITION, fmt.Sprintf("run transition %d (item removed)", t), err)
+			if err != nil {
+				return err
+			}
+		}
+	case REMOVED_ACTION_LABEL:
+		operations := &jiramodels.UpdateOperations{}
+		if err := operations.AddArrayOperation("labels", map[string]string{onRemoved.Label: "add"}); err != nil {
+			return err
+		}
+		_, err := jc.Issue.Update(ctx, key, false, &jiramodels.IssueScheme{}, nil, operations)
+		recordAudit(p, itemId, key, models.AUDIT_ACTION_JIRA_FIELD_UPDATE, fmt.Sprintf(`add label "%s" (item removed)`, onRemoved.Label), err)
+		if err != nil {
+			return err
+		}
+	case REMOVED_ACTION_COMMENT:
+		body := &jiramodels.CommentNodeScheme{Version: 1, Type: "doc"}
+		body.AppendNode(&jiramodels.CommentNodeScheme{
+			Type:    "paragraph",
+			Content: []*jiramodels.CommentNodeScheme{{Type: "text", Text: onRemoved.Comment}},
+		})
+		_, _, err := jc.Issue.Comment.Add(ctx, key, &jiramodels.CommentPayloadScheme{Body: body}, nil)
+		recordAudit(p, itemId, key, models.AUDIT_ACTION_JIRA_COMMENT, fmt.Sprintf(`add comment "%s" (item removed)`, onRemoved.Comment), err)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func updateJiraIssueFromGhIssueWithUrl(
+	ctx context.Context,
 	config Config,
 	projPos int,
 	jc *jira.Client,
+	p models.Project,
 	is models.Issue,
 ) error {
 	urlSplitted := strings.Split(*is.JiraURL, "/")
@@ -554,19 +971,11 @@ func updateJiraIssueFromGhIssueWithUrl(
 	}
 	key := urlSplitted[len(urlSplitted)-1]
 
-	switch *is.Status {
-	case models.STATUS_WIP:
-		transitionToWip(jc, key, projPos, config, is)
-	case models.STATUS_DONE:
-		transitionToWip(jc, key, projPos, config, is)
-		transitionToDone(jc, key, projPos, config, is)
-	}
-
-	return nil
-
+	return transitionToStatus(ctx, jc, p, nil, key, projPos, config, is)
 }
 
 func createJiraIssueFromGhIssueWithoutUrl(
+	ctx context.Context,
 	config Config,
 	projPos int,
 	jc *jira.Client,
@@ -585,12 +994,7 @@ func createJiraIssueFromGhIssueWithoutUrl(
 		assignee = &login
 	}
 
-	var summary string
-	if prefix := config.Projects[projPos].Jira.IssuePrefix; prefix != nil && *prefix != "" {
-		summary = fmt.Sprintf("%s %s", *prefix, is.Title)
-	} else {
-		summary = is.Title
-	}
+	summary := getJiraIssueSummary(config, projPos, is.Title)
 
 	jiraIssue := &jiramodels.IssueScheme{Fields: &jiramodels.IssueFieldsScheme{
 		IssueType: &jiramodels.IssueTypeScheme{Name: *is.JiraIssueType},
@@ -605,24 +1009,128 @@ func createJiraIssueFromGhIssueWithoutUrl(
 	if assignee != nil {
 		jiraIssue.Fields.Assignee = &jiramodels.UserScheme{AccountID: *assignee}
 	}
-	result, _, err := jc.Issue.Create(context.Background(), jiraIssue, jiraIssueCustomFields)
+
+	// the operation is recorded before running any step, so if any of the
+	// following steps fails it is resumed from the last completed one
+	op, err := p.BeginOperation(is.GitHubID, models.OPERATION_KIND_CREATE_ISSUE, is)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(config.Projects[projPos].Jira.Subdomain)
-	url := fmt.Sprintf("https://%s.atlassian.net/browse/%s", config.Projects[projPos].Jira.Subdomain, result.Key)
+	if !op.HasCompleted(OPERATION_STEP_JIRA_CREATED) {
+		// an issue might have been created in a previous execution that stopped
+		// before the step was completed, issues are created with the item label
+		// and property so it can be found before creating another one
+		key, err := findJiraIssueByItem(ctx, jc, config, projPos, p.ID, is.GitHubID)
+		if err != nil {
+			return err
+		}
+		if key == "" {
+			key, err = createJiraIssue(ctx, jc, p, is.GitHubID, jiraIssue, jiraIssueCustomFields)
+			recordAudit(p, is.GitHubID, key, models.AUDIT_ACTION_JIRA_CREATE, fmt.Sprintf(`create %s "%s"`, *is.JiraIssueType, summary), err)
+			if err != nil {
+				return err
+			}
+			metrics.IssueCreated(config.Projects[projPos].Name)
+		}
+		op.JiraKey = &key
+		if err := p.CompleteOperationStep(op, OPERATION_STEP_JIRA_CREATED); err != nil {
+			return err
+		}
+	}
+	key := *op.JiraKey
+
+	if !op.HasCompleted(OPERATION_STEP_JIRA_STAMPED) {
+		if err := stampJiraIssue(ctx, jc, p, key, is.GitHubID); err != nil {
+			return fmt.Errorf("failed to set jira issue property: %w", err)
+		}
+		if err := p.CompleteOperationStep(op, OPERATION_STEP_JIRA_STAMPED); err != nil {
+			return err
+		}
+	}
+
+	url := getJiraIssueUrl(config, projPos, key)
 
-	_, _, err = gh.UpdateProjectItemField(is.GitHubProjectID, is.GitHubID, p.Fields.JiraURL, github.PROJECT_FIELD_TEXT, url)
+	if !op.HasCompleted(OPERATION_STEP_GITHUB_WRITTEN) {
+		if err := writeJiraUrlToGithub(ctx, gh, p, is.GitHubID, key, url); err != nil {
+			return err
+		}
+		if err := p.CompleteOperationStep(op, OPERATION_STEP_GITHUB_WRITTEN); err != nil {
+			return err
+		}
+	}
+
+	if !op.HasCompleted(OPERATION_STEP_STORED) {
+		if _, err := p.UpsertIssue(
+			is.GitHubID,
+			is.Title,
+			is.Status,
+			&url,
+			is.JiraIssueType,
+			is.Repository,
+			is.Estimate,
+			&is.Assignees,
+		); err != nil {
+			return err
+		}
+		if err := p.CompleteOperationStep(op, OPERATION_STEP_STORED); err != nil {
+			return err
+		}
+	}
+
+	if !op.HasCompleted(OPERATION_STEP_JIRA_TRANSITIONED) {
+		if err := transitionToStatus(ctx, jc, p, op, key, projPos, config, is); err != nil {
+			return err
+		}
+		if err := p.CompleteOperationStep(op, OPERATION_STEP_JIRA_TRANSITIONED); err != nil {
+			return err
+		}
+	}
+
+	return p.FinishOperation(op)
+}
+
+// transitionJiraIssueFromDiff transitions a jira issue to the new status of
+// its GitHub item and stores the new status.
+func transitionJiraIssueFromDiff(
+	ctx context.Context,
+	config Config,
+	projPos int,
+	jc *jira.Client,
+	p models.Project,
+	key string,
+	diff models.Diff,
+) error {
+	is := *diff.Issue
+
+	op, err := p.BeginOperation(is.GitHubID, models.OPERATION_KIND_TRANSITION_ISSUE, is)
 	if err != nil {
 		return err
 	}
 
+	if *diff.PrevStatus == models.STATUS_TODO && !op.HasCompleted(OPERATION_STEP_JIRA_TRANSITIONED_TO_WIP) {
+		if err := transitionToWip(ctx, jc, p, op, key, projPos, config, is); err != nil {
+			return err
+		}
+		if err := p.CompleteOperationStep(op, OPERATION_STEP_JIRA_TRANSITIONED_TO_WIP); err != nil {
+			return err
+		}
+	}
+
+	if diff.NewStatus == models.STATUS_DONE && !op.HasCompleted(OPERATION_STEP_JIRA_TRANSITIONED_TO_DONE) {
+		if err := transitionToDone(ctx, jc, p, op, key, projPos, config, is); err != nil {
+			return err
+		}
+		if err := p.CompleteOperationStep(op, OPERATION_STEP_JIRA_TRANSITIONED_TO_DONE); err != nil {
+			return err
+		}
+	}
+
 	if _, err := p.UpsertIssue(
 		is.GitHubID,
 		is.Title,
 		is.Status,
-		&url,
+		is.JiraURL,
 		is.JiraIssueType,
 		is.Repository,
 		is.Estimate,
@@ -631,60 +1139,136 @@ func createJiraIssueFromGhIssueWithoutUrl(
 		return err
 	}
 
-	switch *is.Status {
-	case models.STATUS_WIP:
-		transitionToWip(jc, result.Key, projPos, config, is)
-	case models.STATUS_DONE:
-		transitionToWip(jc, result.Key, projPos, config, is)
-		transitionToDone(jc, result.Key, projPos, config, is)
+	return p.FinishOperation(op)
+}
+
+// resumePendingOperations resumes the issue creations that didn't complete
+// every step in a previous execution.
+func resumePendingOperations(
+	ctx context.Context,
+	config Config,
+	projPos int,
+	jc *jira.Client,
+	gh *github.GitHubClient,
+	p models.Project,
+	assignees map[string]string,
+	log *logrus.Logger,
+) error {
+	projectCfg := config.Projects[projPos]
+
+	ops, err := p.GetPendingOperations()
+	if err != nil {
+		return err
 	}
+
+	for _, op := range ops {
+		if shuttingDown(ctx) {
+			return nil
+		}
+		if op.Kind != models.OPERATION_KIND_CREATE_ISSUE {
+			// transitions are resumed once their diff shows up again
+			continue
+		}
+
+		is, err := op.Issue()
+		if err != nil {
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "operationId": op.ID}).Errorln("decoding pending operation payload failed")
+			continue
+		}
+
+		log.WithFields(logrus.Fields{"project": projectCfg.Name, "operationId": op.ID, "itemId": op.GitHubID, "completedSteps": op.CompletedSteps}).Infoln("resuming pending operation")
+		if err := createJiraIssueFromGhIssueWithoutUrl(ctx, config, projPos, jc, gh, p, *is, assignees); err != nil {
+			log.WithFields(logrus.Fields{"err": err, "project": projectCfg.Name, "operationId": op.ID}).Errorln("resuming pending operation failed")
+		}
+	}
+
 	return nil
 }
 
-func transitionToWip(jc *jira.Client, key string, pos int, config Config, is models.Issue) {
+// getIssueTypeConfig retrieves the config of a jira issue type, if the issue
+// type is not configured *IssueTypeConfig will be nil.
+func getIssueTypeConfig(config Config, pos int, issueType *string) *IssueTypeConfig {
 	issueTypes := helpers.FilterSlice(
 		config.Projects[pos].Jira.Issues,
-		func(it struct {
-			Type              string `yaml:"type"`
-			TransitionsToWIP  []int  `yaml:"transitionsToWip"`
-			TransitionsToDone []int  `yaml:"transitionsToDone"`
-		}) bool {
-			if is.JiraIssueType == nil {
+		func(it IssueTypeConfig) bool {
+			if issueType == nil {
 				return false
 			}
-			return it.Type == *is.JiraIssueType
+			return it.Type == *issueType
 		})
 	if len(issueTypes) == 0 {
-		return
+		return nil
 	}
-	transitions := issueTypes[len(issueTypes)-1].TransitionsToWIP
-	for _, t := range transitions {
-		_, err := jc.Issue.Move(context.Background(), key, fmt.Sprintf("%d", t), nil)
-		// TODO: log the error
-		fmt.Println(err)
+	return &issueTypes[len(issueTypes)-1]
+}
+
+// transitionToStatus runs the transitions that take a jira issue to the
+// status of its GitHub item. Transitions already completed within the given
+// operation, if any, are skipped.
+func transitionToStatus(ctx context.Context, jc *jira.Client, p models.Project, op *models.Operation, key string, pos int, config Config, is models.Issue) error {
+	switch *is.Status {
+	case models.STATUS_WIP:
+		return transitionToWip(ctx, jc, p, op, key, pos, config, is)
+	case models.STATUS_DONE:
+		if err := transitionToWip(ctx, jc, p, op, key, pos, config, is); err != nil {
+			return err
+		}
+		return transitionToDone(ctx, jc, p, op, key, pos, config, is)
 	}
+	return nil
 }
 
-func transitionToDone(jc *jira.Client, key string, pos int, config Config, is models.Issue) {
-	issueTypes := helpers.FilterSlice(
-		config.Projects[pos].Jira.Issues,
-		func(it struct {
-			Type              string `yaml:"type"`
-			TransitionsToWIP  []int  `yaml:"transitionsToWip"`
-			TransitionsToDone []int  `yaml:"transitionsToDone"`
-		}) bool {
-			if is.JiraIssueType == nil {
-				return false
+func transitionToWip(ctx context.Context, jc *jira.Client, p models.Project, op *models.Operation, key string, pos int, config Config, is models.Issue) error {
+	issueType := getIssueTypeConfig(config, pos, is.JiraIssueType)
+	if issueType == nil {
+		return nil
+	}
+	return runTransitions(ctx, jc, p, op, OPERATION_STEP_JIRA_TRANSITIONED_TO_WIP, key, pos, config, is, issueType.TransitionsToWIP, "to in progress")
+}
+
+func transitionToDone(ctx context.Context, jc *jira.Client, p models.Project, op *models.Operation, key string, pos int, config Config, is models.Issue) error {
+	issueType := getIssueTypeConfig(config, pos, is.JiraIssueType)
+	if issueType == nil {
+		return nil
+	}
+	return runTransitions(ctx, jc, p, op, OPERATION_STEP_JIRA_TRANSITIONED_TO_DONE, key, pos, config, is, issueType.TransitionsToDone, "to done")
+}
+
+// runTransitions runs the given transitions in order and stops at the first
+// failure, as the following ones start from the status it leads to. Within
+// an operation every transition is its own step ("<step>:<transition id>"),
+// so a resumed operation never runs again a transition that succeeded.
+func runTransitions(ctx context.Context, jc *jira.Client, p models.Project, op *models.Operation, step, key string, pos int, config Config, is models.Issue, transitions []int, target string) error {
+	for _, t := range getPendingTransitions(op, step, transitions) {
+		_, err := jc.Issue.Move(ctx, key, fmt.Sprintf("%d", t), nil)
+		metrics.Transitioned(config.Projects[pos].Name, err)
+		recordAudit(p, is.GitHubID, key, models.AUDIT_ACTION_JIRA_TRANSITION, fmt.Sprintf("run transition %d (%s)", t, target), err)
+		if err != nil {
+			return fmt.Errorf("transition %d of %s failed: %w", t, key, err)
+		}
+
+		if op != nil {
+			if err := p.CompleteOperationStep(op, getTransitionStep(step, t)); err != nil {
+				return err
 			}
-			return it.Type == *is.JiraIssueType
-		})
-	if len(issueTypes) == 0 {
-		return
+		}
 	}
-	transitions := issueTypes[len(issueTypes)-1].TransitionsToDone
-	for _, t := range transitions {
-		_, err := jc.Issue.Move(context.Background(), key, fmt.Sprintf("%d", t), nil)
-		// TODO: log the error
-		fmt.Println(err)
+	return nil
+}
+
+// getTransitionStep returns the operation step of a transition run within
+// the given step.
+func getTransitionStep(step string, transition int) string {
+	return fmt.Sprintf("%s:%d", step, transition)
+}
+
+// getPendingTransitions leaves out the transitions the given operation, if
+// any, already completed within the given step.
+func getPendingTransitions(op *models.Operation, step string, transitions []int) []int {
+	if op == nil {
+		return transitions
 	}
+	return helpers.FilterSlice(transitions, func(t int) bool {
+		return !op.HasCompleted(getTransitionStep(step, t))
+	})
 }
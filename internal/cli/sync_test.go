@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jira "github.com/ctreminiom/go-atlassian/jira/v3"
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTransitionJiraIssueFromDiffResume(t *testing.T) {
+	// transition 21 fails the first time it runs, transition 11 must never
+	// run twice as it leaves the issue in another status
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/issue/ABC-1/transitions") {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Transition struct {
+				ID string `json:"id"`
+			} `json:"transition"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		id := body.Transition.ID
+		calls[id]++
+		if id == "21" && calls[id] == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	jc, err := jira.New(nil, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal([]byte(`
+sync:
+  - name: a
+    jira:
+      issues:
+        - type: Task
+          transitionsToWip: [11, 21]
+`), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := models.InitializeInMemory(models.DEFAULT_BACKEND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	p, err := m.Projects.Upsert("P", "a", "b", "c", "d", "e", "f", "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	todo, wip, issueType := models.STATUS_TODO, models.STATUS_WIP, "Task"
+	if _, err := p.UpsertIssue("item", "item", &todo, nil, &issueType, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	diff := models.Diff{
+		PrevStatus: &todo,
+		NewStatus:  wip,
+		Issue:      &models.Issue{GitHubID: "item", Title: "item", Status: &wip, JiraIssueType: &issueType},
+	}
+
+	ctx := context.Background()
+	if err := transitionJiraIssueFromDiff(ctx, config, 0, jc, *p, "ABC-1", diff); err == nil {
+		t.Fatal("got no error, want the failed transition one")
+	}
+	if ops, err := p.GetPendingOperations(); err != nil || len(ops) != 1 {
+		t.Fatalf("got pending operations %v (err %v), want the failed one", ops, err)
+	}
+
+	// the next sync resumes the operation from the failed transition
+	if err := transitionJiraIssueFromDiff(ctx, config, 0, jc, *p, "ABC-1", diff); err != nil {
+		t.Fatal(err)
+	}
+	if calls["11"] != 1 || calls["21"] != 2 {
+		t.Errorf("got transition calls %v, want 11 once and 21 twice", calls)
+	}
+	if ops, err := p.GetPendingOperations(); err != nil || len(ops) != 0 {
+		t.Errorf("got pending operations %v (err %v), want none", ops, err)
+	}
+	if is, err := p.GetIssue("item"); err != nil || is == nil || is.Status == nil || *is.Status != wip {
+		t.Errorf("got issue %v (err %v), want it in %q", is, err, wip)
+	}
+}
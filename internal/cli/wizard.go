@@ -0,0 +1,540 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	jira "github.com/ctreminiom/go-atlassian/jira/v3"
+	jiramodels "github.com/ctreminiom/go-atlassian/pkg/infra/models"
+	"github.com/iolave/jira-tickets-from-gh/internal/github"
+	"github.com/iolave/jira-tickets-from-gh/internal/helpers"
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// jira status categories
+const (
+	JIRA_STATUS_CATEGORY_WIP  = "indeterminate"
+	JIRA_STATUS_CATEGORY_DONE = "done"
+)
+
+type ConfigInitCmd struct {
+	Output string  `arg:"--output,-o" default:"./config.yml" help:"path of the config file to write" placeholder:"<PATH>"`
+	Force  bool    `arg:"--force" help:"overwrite the config file if it already exists"`
+	Org    *string `arg:"--org" help:"GitHub organization owning the project" placeholder:"<ORG>"`
+	User   *string `arg:"-u,--user" help:"GitHub user owning the project" placeholder:"<USER>"`
+}
+
+// configInitAnswers holds what the config init wizard writes into the
+// config file.
+type configInitAnswers struct {
+	Name          string
+	ProjectID     string
+	ProjectTitle  string
+	Subdomain     string
+	ProjectKey    string
+	EstimateField *jiramodels.IssueFieldScheme
+	IssuePrefix   string
+	Issues        []configInitIssue
+}
+
+// configInitIssue is an issue type config along with the statuses its
+// transitions go through, written as comments.
+type configInitIssue struct {
+	IssueTypeConfig
+	WipStatuses  []string
+	DoneStatuses []string
+}
+
+// ConfigInitAction asks for the GitHub and Jira projects to sync, detects
+// their issue types, transitions and estimate field, and writes a config
+// file with a single sync project.
+func ConfigInitAction(args Cmd) {
+	if args.Config == nil || args.Config.Init == nil {
+		exitOnInvalidCall("config init")
+	}
+	cmd := args.Config.Init
+
+	if cmd.Org != nil && cmd.User != nil {
+		exitOnConflictingFlags("--org", "--user")
+	}
+	if _, err := os.Stat(cmd.Output); err == nil && !cmd.Force {
+		exitFromErr(fmt.Errorf(`config file "%s" already exists, use --force to overwrite it`, cmd.Output))
+	}
+	if args.GithubToken == nil {
+		err := errors.New(`please set the "GITHUB_TOKEN" env variable`)
+		exitFromErr(err)
+	}
+
+	ctx := context.Background()
+	p := newPrompter(os.Stdin, os.Stdout)
+	gh := github.New(*args.GithubToken)
+	answers := configInitAnswers{}
+
+	validName := regexp.MustCompile("^[a-zA-Z0-9_]+$")
+	for {
+		answers.Name = p.ask("sync project name", "my_project")
+		if validName.MatchString(answers.Name) {
+			break
+		}
+		fmt.Println(`the name can only have the characters [a-zA-Z0-9_]`)
+	}
+
+	ghIssueTypes, err := askGithubProject(ctx, p, gh, cmd.Org, cmd.User, &answers)
+	if err != nil {
+		exitFromErr(err)
+	}
+
+	token, email, err := getProjectJiraCreds(args, answers.Name)
+	if err != nil {
+		exitFromErr(err)
+	}
+	var jc *jira.Client
+	for {
+		answers.Subdomain = p.ask("jira subdomain (<subdomain>.atlassian.net)", "")
+		if jc, err = newJiraClient(answers.Subdomain, email, token); err == nil {
+			if _, _, err = jc.MySelf.Details(ctx, nil); err == nil {
+				break
+			}
+		}
+		fmt.Printf("couldn't authenticate against %s.atlassian.net as %s: %s\n", answers.Subdomain, email, err)
+	}
+
+	project, err := askJiraProject(ctx, p, jc, &answers)
+	if err != nil {
+		exitFromErr(err)
+	}
+	if err := askIssueTypes(ctx, p, jc, project, ghIssueTypes, &answers); err != nil {
+		exitFromErr(err)
+	}
+	if err := askEstimateField(ctx, p, jc, &answers); err != nil {
+		exitFromErr(err)
+	}
+	answers.IssuePrefix = p.ask("prefix added to the jira issues summary, if any", "")
+
+	if err := writeInitConfig(cmd.Output, answers); err != nil {
+		exitFromErr(err)
+	}
+	log := newLogger(logrus.InfoLevel)
+	log.SetOutput(io.Discard)
+	if _, err := readConfig(cmd.Output, log); err != nil {
+		exitFromErr(fmt.Errorf("the written config is not valid: %w", err))
+	}
+
+	fmt.Printf("wrote %s, check it with \"config validate --config %s --online\"\n", cmd.Output, cmd.Output)
+}
+
+// askGithubProject lists the projects of the GitHub organization or user and
+// asks which one to sync. It returns the options of the project "Jira issue
+// type" field.
+func askGithubProject(ctx context.Context, p *prompter, gh *github.GitHubClient, org, user *string, answers *configInitAnswers) ([]string, error) {
+	type ghProject struct{ ID, Title string }
+	projects := []ghProject{}
+
+	listOrg := func(login string) error {
+		result, _, err := gh.ListOrganizationProjects(ctx, login)
+		for _, n := range result.Data.Organization.Projects.Nodes {
+			projects = append(projects, ghProject{n.ID, n.Title})
+		}
+		return err
+	}
+	listUser := func(login string) error {
+		result, _, err := gh.ListUserProjects(ctx, login)
+		for _, n := range result.Data.User.Projects.Nodes {
+			projects = append(projects, ghProject{n.ID, n.Title})
+		}
+		return err
+	}
+
+	var err error
+	switch {
+	case org != nil:
+		err = listOrg(*org)
+	case user != nil:
+		err = listUser(*user)
+	default:
+		login := p.ask("GitHub organization or user owning the project", "")
+		if err = listOrg(login); err != nil || len(projects) == 0 {
+			err = listUser(login)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing GitHub projects failed: %w", err)
+	}
+	if len(projects) == 0 {
+		return nil, errors.New("no GitHub projects found")
+	}
+
+	options := helpers.MapSlice(projects, func(gp ghProject) string { return fmt.Sprintf("%s (%s)", gp.Title, gp.ID) })
+	chosen := projects[p.choose("GitHub project", options, 0)]
+	answers.ProjectID = chosen.ID
+	answers.ProjectTitle = chosen.Title
+
+	fields, _, err := gh.GetProjectFields(ctx, chosen.ID)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving GitHub project fields failed: %w", err)
+	}
+	required := []string{
+		models.FIELD_NAME_JIRA_URL,
+		models.FIELD_NAME_JIRA_ISSUE_TYPE,
+		models.FIELD_NAME_TITLE,
+		models.FIELD_NAME_ESTIMATE,
+		models.FIELD_NAME_STATUS,
+		models.FIELD_NAME_ASSIGNEES,
+		models.FIELD_NAME_REPO,
+	}
+	issueTypes := []string{}
+	for _, f := range fields.Data.Node.Fields.Nodes {
+		required = slices.DeleteFunc(required, func(name string) bool { return name == f.Name })
+		if f.Name == models.FIELD_NAME_JIRA_ISSUE_TYPE && f.Options != nil {
+			for _, o := range *f.Options {
+				issueTypes = append(issueTypes, o.Name)
+			}
+		}
+	}
+	if len(required) > 0 {
+		fmt.Printf("warning: the GitHub project lacks of the fields [%s], add them before running a sync\n", strings.Join(required, ", "))
+	}
+
+	return issueTypes, nil
+}
+
+// askJiraProject lists the jira projects and asks which one to sync.
+func askJiraProject(ctx context.Context, p *prompter, jc *jira.Client, answers *configInitAnswers) (*jiramodels.ProjectScheme, error) {
+	projects := []*jiramodels.ProjectScheme{}
+	for startAt := 0; ; {
+		page, _, err := jc.Project.Search(ctx, &jiramodels.ProjectSearchOptionsScheme{OrderBy: "key"}, startAt, 50)
+		if err != nil {
+			return nil, fmt.Errorf("listing jira projects failed: %w", err)
+		}
+		projects = append(projects, page.Values...)
+		startAt += len(page.Values)
+		if page.IsLast || len(page.Values) == 0 {
+			break
+		}
+	}
+	if len(projects) == 0 {
+		return nil, errors.New("no jira projects found")
+	}
+
+	options := helpers.MapSlice(projects, func(jp *jiramodels.ProjectScheme) string { return fmt.Sprintf("%s - %s", jp.Key, jp.Name) })
+	answers.ProjectKey = projects[p.choose("jira project", options, 0)].Key
+
+	project, _, err := jc.Project.Get(ctx, answers.ProjectKey, []string{"issueTypes"})
+	if err != nil {
+		return nil, fmt.Errorf("retrieving jira project failed: %w", err)
+	}
+	return project, nil
+}
+
+// askIssueTypes maps the GitHub "Jira issue type" options to the jira project
+// issue types and finds their transitions to the WIP and done statuses.
+// Without options, the issue types to sync are chosen from the jira ones.
+func askIssueTypes(ctx context.Context, p *prompter, jc *jira.Client, project *jiramodels.ProjectScheme, ghIssueTypes []string, answers *configInitAnswers) error {
+	jiraIssueTypes := slices.DeleteFunc(slices.Clone(project.IssueTypes), func(it *jiramodels.IssueTypeScheme) bool { return it.Subtask })
+
+	issueTypes := []*jiramodels.IssueTypeScheme{}
+	if len(ghIssueTypes) > 0 {
+		// the sync creates jira issues of the type named as the GitHub option
+		for _, name := range ghIssueTypes {
+			idx := slices.IndexFunc(jiraIssueTypes, func(it *jiramodels.IssueTypeScheme) bool { return it.Name == name })
+			if idx == -1 {
+				fmt.Printf("warning: GitHub issue type \"%s\" doesn't match any issue type of %s, rename it to one of [%s]\n", name, project.Key, strings.Join(getIssueTypesNames(jiraIssueTypes), ", "))
+				continue
+			}
+			issueTypes = append(issueTypes, jiraIssueTypes[idx])
+		}
+	} else {
+		fmt.Printf("warning: the GitHub project \"%s\" field has no options, add the issue types chosen below to it\n", models.FIELD_NAME_JIRA_ISSUE_TYPE)
+		for _, i := range p.chooseMany("jira issue types to sync", getIssueTypesNames(jiraIssueTypes)) {
+			issueTypes = append(issueTypes, jiraIssueTypes[i])
+		}
+	}
+	if len(issueTypes) == 0 {
+		return errors.New("no issue types to sync")
+	}
+
+	// statuses categories by issue type and status id
+	categories := map[string]map[string]string{}
+	names := map[string]string{}
+	pages, _, err := jc.Project.Statuses(ctx, project.Key)
+	if err != nil {
+		return fmt.Errorf("retrieving jira project statuses failed: %w", err)
+	}
+	for _, page := range pages {
+		categories[page.ID] = map[string]string{}
+		for _, status := range page.Statuses {
+			names[status.ID] = status.Name
+			if status.StatusCategory != nil {
+				categories[page.ID][status.ID] = status.StatusCategory.Key
+			}
+		}
+	}
+
+	scheme, err := getProjectWorkflowScheme(ctx, jc, project.ID)
+	if err != nil {
+		fmt.Println("warning: couldn't read the project workflows (requires the jira administer permission), enter the transition ids by hand")
+	}
+
+	for _, it := range issueTypes {
+		issue := configInitIssue{IssueTypeConfig: IssueTypeConfig{Type: it.Name}}
+
+		var wip, done []int
+		if scheme != nil {
+			workflow := getIssueTypeWorkflow(ctx, jc, scheme, it.ID)
+			if transitions, err := getWorkflowTransitions(ctx, jc, workflow); err == nil {
+				initial := ""
+				for _, t := range transitions {
+					if t.Type == "initial" {
+						initial = t.To
+					}
+				}
+				wipStatus := ""
+				if initial != "" {
+					wip, issue.WipStatuses, wipStatus = findTransitionsPath(transitions, initial, categories[it.ID], JIRA_STATUS_CATEGORY_WIP, names)
+				}
+				if wipStatus != "" {
+					done, issue.DoneStatuses, _ = findTransitionsPath(transitions, wipStatus, categories[it.ID], JIRA_STATUS_CATEGORY_DONE, names)
+				}
+			}
+		}
+
+		issue.TransitionsToWIP = p.askInts(fmt.Sprintf(`"%s" transitions to get to a WIP status`, it.Name), wip)
+		if !slices.Equal(issue.TransitionsToWIP, wip) {
+			issue.WipStatuses = nil
+		}
+		issue.TransitionsToDone = p.askInts(fmt.Sprintf(`"%s" transitions to get from the WIP status to a done status`, it.Name), done)
+		if !slices.Equal(issue.TransitionsToDone, done) {
+			issue.DoneStatuses = nil
+		}
+		answers.Issues = append(answers.Issues, issue)
+	}
+
+	return nil
+}
+
+// findTransitionsPath finds the shortest sequence of transitions from a
+// status to a status of the given category. It returns the transitions ids,
+// the names of the statuses they go through and the reached status id.
+func findTransitionsPath(transitions []*jiramodels.WorkflowTransitionScheme, from string, categories map[string]string, category string, names map[string]string) ([]int, []string, string) {
+	type step struct {
+		status   string
+		ids      []int
+		statuses []string
+	}
+
+	visited := map[string]bool{from: true}
+	queue := []step{{status: from, statuses: []string{names[from]}}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, t := range transitions {
+			if t.Type == "initial" || visited[t.To] {
+				continue
+			}
+			if len(t.From) > 0 && !slices.Contains(t.From, current.status) {
+				continue
+			}
+			id, err := strconv.Atoi(t.ID)
+			if err != nil {
+				continue
+			}
+
+			next := step{
+				status:   t.To,
+				ids:      append(slices.Clone(current.ids), id),
+				statuses: append(slices.Clone(current.statuses), names[t.To]),
+			}
+			if categories[t.To] == category {
+				return next.ids, next.statuses, next.status
+			}
+			visited[t.To] = true
+			queue = append(queue, next)
+		}
+	}
+
+	return nil, nil, ""
+}
+
+// askEstimateField looks for numeric custom fields named like a story points
+// or estimate field and asks which one, if any, stores the estimate.
+func askEstimateField(ctx context.Context, p *prompter, jc *jira.Client, answers *configInitAnswers) error {
+	fields, _, err := jc.Issue.Field.Gets(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving jira fields failed: %w", err)
+	}
+
+	candidates := []*jiramodels.IssueFieldScheme{}
+	for _, f := range fields {
+		name := strings.ToLower(f.Name)
+		if !f.Custom || f.Schema == nil || f.Schema.Type != "number" {
+			continue
+		}
+		if strings.Contains(name, "story point") {
+			candidates = slices.Insert(candidates, 0, f)
+		} else if strings.Contains(name, "estimate") {
+			candidates = append(candidates, f)
+		}
+	}
+	if len(candidates) == 0 {
+		fmt.Println("warning: no story points field found, GitHub estimates won't be synced")
+		return nil
+	}
+
+	options := helpers.MapSlice(candidates, func(f *jiramodels.IssueFieldScheme) string { return fmt.Sprintf("%s (%s)", f.Name, f.ID) })
+	options = append(options, "none")
+	if i := p.choose("jira field storing the estimate", options, 0); i < len(candidates) {
+		answers.EstimateField = candidates[i]
+	}
+	return nil
+}
+
+// writeInitConfig writes the config file with comments.
+func writeInitConfig(path string, answers configInitAnswers) error {
+	var b strings.Builder
+	fmt.Fprintln(&b, "# written by \"jira-tickets-from-gh config init\", see the README for every option")
+	fmt.Fprintln(&b, "sync:")
+	fmt.Fprintf(&b, "  - name: %s\n", answers.Name)
+	fmt.Fprintln(&b, "    # runs the project every 5 minutes, without a schedule (or sleepTime) it syncs once")
+	fmt.Fprintln(&b, "    # schedule: 5m")
+	fmt.Fprintln(&b, "    # maps GitHub users to Jira accounts, used to assign the Jira issues")
+	fmt.Fprintln(&b, "    # assignees:")
+	fmt.Fprintln(&b, "    #   - jiraEmail: email@example.com")
+	fmt.Fprintln(&b, "    #     ghUser: octocat")
+	fmt.Fprintln(&b, "    github:")
+	fmt.Fprintf(&b, "      # %s\n", answers.ProjectTitle)
+	fmt.Fprintf(&b, "      projectId: %s\n", yamlString(answers.ProjectID))
+	fmt.Fprintln(&b, "    jira:")
+	fmt.Fprintf(&b, "      subdomain: %s\n", yamlString(answers.Subdomain))
+	fmt.Fprintf(&b, "      projectKey: %s\n", yamlString(answers.ProjectKey))
+	if answers.EstimateField != nil {
+		fmt.Fprintf(&b, "      # %s\n", answers.EstimateField.Name)
+		fmt.Fprintf(&b, "      estimateField: %s\n", yamlString(answers.EstimateField.ID))
+	}
+	if answers.IssuePrefix != "" {
+		fmt.Fprintf(&b, "      issuePrefix: %s\n", yamlString(answers.IssuePrefix))
+	}
+	fmt.Fprintln(&b, "      # issue types as named in the GitHub \"Jira issue type\" field, transitions run in order")
+	fmt.Fprintln(&b, "      issues:")
+	for _, issue := range answers.Issues {
+		fmt.Fprintf(&b, "        - type: %s\n", yamlString(issue.Type))
+		if len(issue.WipStatuses) > 0 {
+			fmt.Fprintf(&b, "          # %s\n", strings.Join(issue.WipStatuses, " -> "))
+		}
+		fmt.Fprintf(&b, "          transitionsToWip: %s\n", yamlInts(issue.TransitionsToWIP))
+		if len(issue.DoneStatuses) > 0 {
+			fmt.Fprintf(&b, "          # %s\n", strings.Join(issue.DoneStatuses, " -> "))
+		}
+		fmt.Fprintf(&b, "          transitionsToDone: %s\n", yamlInts(issue.TransitionsToDone))
+	}
+	fmt.Fprintln(&b, "      # what to do with the Jira issue of an item removed from the GitHub project")
+	fmt.Fprintln(&b, "      onRemoved:")
+	fmt.Fprintln(&b, "        action: none")
+
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// yamlString quotes a string for a yaml file, json strings being valid yaml.
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func yamlInts(ints []int) string {
+	return fmt.Sprintf("[%s]", strings.Join(helpers.MapSlice(ints, strconv.Itoa), ", "))
+}
+
+func getIssueTypesNames(issueTypes []*jiramodels.IssueTypeScheme) []string {
+	return helpers.MapSlice(issueTypes, func(it *jiramodels.IssueTypeScheme) string { return it.Name })
+}
+
+// prompter asks questions through a line based input, exiting when the
+// input is closed.
+type prompter struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{scanner: bufio.NewScanner(in), out: out}
+}
+
+// ask returns the answer to a question, or def when left empty.
+func (p *prompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	if !p.scanner.Scan() {
+		fmt.Fprintln(p.out)
+		exitFromErr(errors.New("input closed"))
+	}
+	if answer := strings.TrimSpace(p.scanner.Text()); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// choose lists the options and returns the position of the chosen one.
+func (p *prompter) choose(question string, options []string, def int) int {
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+	for {
+		n, err := strconv.Atoi(p.ask(question, strconv.Itoa(def+1)))
+		if err == nil && n >= 1 && n <= len(options) {
+			return n - 1
+		}
+		fmt.Fprintf(p.out, "please answer a number from 1 to %d\n", len(options))
+	}
+}
+
+// chooseMany lists the options and returns the positions of the chosen ones,
+// answered as comma separated numbers.
+func (p *prompter) chooseMany(question string, options []string) []int {
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+	for {
+		ns := p.askInts(question+" (comma separated)", nil)
+		if len(ns) > 0 && !slices.ContainsFunc(ns, func(n int) bool { return n < 1 || n > len(options) }) {
+			return helpers.MapSlice(ns, func(n int) int { return n - 1 })
+		}
+		fmt.Fprintf(p.out, "please answer numbers from 1 to %d\n", len(options))
+	}
+}
+
+// askInts returns the comma separated integers answered, or def when left
+// empty.
+func (p *prompter) askInts(question string, def []int) []int {
+	for {
+		answer := p.ask(question, strings.Join(helpers.MapSlice(def, strconv.Itoa), ","))
+		ints := []int{}
+		valid := true
+		for _, s := range strings.Split(answer, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				valid = false
+				break
+			}
+			ints = append(ints, n)
+		}
+		if valid {
+			return ints
+		}
+		fmt.Fprintln(p.out, "please answer comma separated numbers")
+	}
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	jiramodels "github.com/ctreminiom/go-atlassian/pkg/infra/models"
+)
+
+func TestFindTransitionsPath(t *testing.T) {
+	// 1: To Do, 2: Review, 3: In Progress, 4: Done, 5: Blocked
+	names := map[string]string{"1": "To Do", "2": "Review", "3": "In Progress", "4": "Done", "5": "Blocked"}
+	categories := map[string]string{
+		"1": "new",
+		"2": JIRA_STATUS_CATEGORY_WIP,
+		"3": JIRA_STATUS_CATEGORY_WIP,
+		"4": JIRA_STATUS_CATEGORY_DONE,
+		"5": "new",
+	}
+	transition := func(id, to string, from ...string) *jiramodels.WorkflowTransitionScheme {
+		return &jiramodels.WorkflowTransitionScheme{ID: id, To: to, From: from}
+	}
+
+	tests := []struct {
+		name        string
+		transitions []*jiramodels.WorkflowTransitionScheme
+		from        string
+		category    string
+		wantIds     []int
+		wantNames   []string
+		wantStatus  string
+	}{
+		{
+			name:        "direct transition",
+			transitions: []*jiramodels.WorkflowTransitionScheme{transition("11", "3", "1")},
+			from:        "1",
+			category:    JIRA_STATUS_CATEGORY_WIP,
+			wantIds:     []int{11},
+			wantNames:   []string{"To Do", "In Progress"},
+			wantStatus:  "3",
+		},
+		{
+			name: "shortest path through other statuses",
+			transitions: []*jiramodels.WorkflowTransitionScheme{
+				transition("11", "5", "1"),
+				transition("21", "3", "5"),
+				transition("31", "4", "3"),
+				transition("41", "1", "5"),
+			},
+			from:       "1",
+			category:   JIRA_STATUS_CATEGORY_DONE,
+			wantIds:    []int{11, 21, 31},
+			wantNames:  []string{"To Do", "Blocked", "In Progress", "Done"},
+			wantStatus: "4",
+		},
+		{
+			name: "shorter path wins over the first listed one",
+			transitions: []*jiramodels.WorkflowTransitionScheme{
+				transition("11", "5", "1"),
+				transition("21", "4", "5"),
+				transition("31", "4", "1"),
+			},
+			from:       "1",
+			category:   JIRA_STATUS_CATEGORY_DONE,
+			wantIds:    []int{31},
+			wantNames:  []string{"To Do", "Done"},
+			wantStatus: "4",
+		},
+		{
+			name: "global transitions apply from any status",
+			transitions: []*jiramodels.WorkflowTransitionScheme{
+				transition("11", "2"),
+			},
+			from:       "5",
+			category:   JIRA_STATUS_CATEGORY_WIP,
+			wantIds:    []int{11},
+			wantNames:  []string{"Blocked", "Review"},
+			wantStatus: "2",
+		},
+		{
+			name: "initial and non numeric transitions are skipped",
+			transitions: []*jiramodels.WorkflowTransitionScheme{
+				{ID: "1", To: "3", Type: "initial"},
+				transition("abc", "3", "1"),
+				transition("21", "2", "1"),
+			},
+			from:       "1",
+			category:   JIRA_STATUS_CATEGORY_WIP,
+			wantIds:    []int{21},
+			wantNames:  []string{"To Do", "Review"},
+			wantStatus: "2",
+		},
+		{
+			name: "cycles without the category end without a path",
+			transitions: []*jiramodels.WorkflowTransitionScheme{
+				transition("11", "5", "1"),
+				transition("21", "1", "5"),
+			},
+			from:     "1",
+			category: JIRA_STATUS_CATEGORY_DONE,
+		},
+		{
+			name:     "no transitions",
+			from:     "1",
+			category: JIRA_STATUS_CATEGORY_WIP,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, statuses, status := findTransitionsPath(tt.transitions, tt.from, categories, tt.category, names)
+			if !reflect.DeepEqual(ids, tt.wantIds) {
+				t.Errorf("got transitions %v, want %v", ids, tt.wantIds)
+			}
+			if !reflect.DeepEqual(statuses, tt.wantNames) {
+				t.Errorf("got statuses %v, want %v", statuses, tt.wantNames)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("got status %q, want %q", status, tt.wantStatus)
+			}
+		})
+	}
+}
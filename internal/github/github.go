@@ -2,19 +2,39 @@ package github
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/metrics"
 )
 
 type GitHubClient struct {
 	client *http.Client
-	token  string
+	tokens TokenSource
+
+	mu        sync.Mutex
+	rateLimit *RateLimit // as of the last response, nil until then
+}
+
+// RateLimit is the GitHub api rate limit of a token.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
 }
 
+// ErrRateLimited is returned, without sending the request, while the rate
+// limit of the client token is spent.
+var ErrRateLimited = errors.New("github rate limit spent")
+
 type Error struct {
 	Message string  `json:"message"`
 	Type    *string `json:"type"`
@@ -25,10 +45,11 @@ func (e Error) ToError() error {
 		return fmt.Errorf("%s: %s", *e.Type, e.Message)
 	}
 
-	return fmt.Errorf("%s: %s", *e.Type, e.Message)
+	return errors.New(e.Message)
 }
 
-func getErrorFromErrors(errs *[]Error) error {
+// GetErrorFromErrors turns the first graphql error into an error.
+func GetErrorFromErrors(errs *[]Error) error {
 	if errs == nil {
 		return nil
 	}
@@ -43,22 +64,90 @@ func getErrorFromErrors(errs *[]Error) error {
 }
 
 func New(token string) *GitHubClient {
-	transport := http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
+	return &GitHubClient{
+		client: newHttpClient(),
+		tokens: staticToken(token),
 	}
-	client := &http.Client{
-		Transport: &transport,
+}
+
+// NewApp creates a client authenticated as a GitHub App installation, its
+// installation tokens are minted and refreshed as needed.
+func NewApp(appID, installationID int64, privateKey []byte) (*GitHubClient, error) {
+	client := newHttpClient()
+	tokens, err := NewAppTokenSource(appID, installationID, privateKey, client)
+	if err != nil {
+		return nil, err
 	}
 
 	return &GitHubClient{
 		client: client,
-		token:  token,
+		tokens: tokens,
+	}, nil
+}
+
+// newHttpClient returns a client with the default transport settings (proxy
+// from the environment and tls verification) that records its requests.
+func newHttpClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	return &http.Client{
+		Transport: metrics.NewTransport(metrics.SERVICE_GITHUB, transport),
+	}
+}
+
+// Token returns the token the client requests are authenticated with.
+func (c *GitHubClient) Token(ctx context.Context) (string, error) {
+	return c.tokens.Token(ctx)
+}
+
+// RateLimit returns the rate limit of the client token as of the last
+// response, false if no response was received yet.
+func (c *GitHubClient) RateLimit() (RateLimit, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rateLimit == nil {
+		return RateLimit{}, false
+	}
+	return *c.rateLimit, true
+}
+
+// checkRateLimit fails while the rate limit of the client token is spent.
+func (c *GitHubClient) checkRateLimit() error {
+	rateLimit, ok := c.RateLimit()
+	if ok && rateLimit.Remaining == 0 && time.Now().Before(rateLimit.Reset) {
+		return fmt.Errorf("%w until %s", ErrRateLimited, rateLimit.Reset.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// updateRateLimit records the rate limit headers of a response.
+func (c *GitHubClient) updateRateLimit(res *http.Response) {
+	limit, err := strconv.Atoi(res.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateLimit = &RateLimit{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}
 }
 
-func (c *GitHubClient) request(query string, result any) (*http.Response, error) {
+func (c *GitHubClient) request(ctx context.Context, query string, result any) (*http.Response, error) {
+	if err := c.checkRateLimit(); err != nil {
+		return nil, err
+	}
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var requestBody bytes.Buffer
 	requestBodyObj := struct {
 		Query     string                 `json:"query"`
@@ -71,15 +160,16 @@ func (c *GitHubClient) request(query string, result any) (*http.Response, error)
 	if err := json.NewEncoder(&requestBody).Encode(requestBodyObj); err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", &requestBody)
-	req.Header.Add("authorization", fmt.Sprintf("Bearer %s", c.token))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.github.com/graphql", &requestBody)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Add("authorization", fmt.Sprintf("Bearer %s", token))
 	res, err := c.client.Do(req)
 	if err != nil {
 		return res, err
 	}
+	c.updateRateLimit(res)
 
 	if res.StatusCode != http.StatusOK {
 		return res, errors.New("failed to send github graphql request")
@@ -96,3 +186,48 @@ func (c *GitHubClient) request(query string, result any) (*http.Response, error)
 
 	return res, nil
 }
+
+type GetViewerResult struct {
+	Errors *[]Error `json:"errors"`
+	Data   struct {
+		Viewer struct {
+			Login string `json:"login"`
+		} `json:"viewer"`
+	} `json:"data"`
+}
+
+// GetViewer retrieves the user the client token belongs to.
+func (c *GitHubClient) GetViewer(ctx context.Context) (GetViewerResult, *http.Response, error) {
+	query := `query{ viewer { login } }`
+
+	var result GetViewerResult
+
+	res, err := c.request(ctx, query, &result)
+	if err != nil {
+		return result, res, err
+	}
+	err = GetErrorFromErrors(result.Errors)
+
+	return result, res, err
+}
+
+// GetTokenScopes returns the scopes of a classic token as reported by a
+// response, false when the response doesn't report them (i.e. fine-grained
+// tokens).
+func GetTokenScopes(res *http.Response) ([]string, bool) {
+	if res == nil {
+		return nil, false
+	}
+	header, ok := res.Header["X-Oauth-Scopes"]
+	if !ok || len(header) == 0 {
+		return nil, false
+	}
+
+	scopes := []string{}
+	for _, scope := range strings.Split(header[0], ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true
+}
@@ -1,6 +1,7 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -33,7 +34,7 @@ type ListUserProjectsResult struct {
 	} `json:"data"`
 }
 
-func (c *GitHubClient) ListUserProjects(user string) (ListUserProjectsResult, *http.Response, error) {
+func (c *GitHubClient) ListUserProjects(ctx context.Context, user string) (ListUserProjectsResult, *http.Response, error) {
 	query := fmt.Sprintf(`query{
 		user(login:"%s") {
 			projectsV2(first:100){ nodes { id title } }
@@ -42,11 +43,11 @@ func (c *GitHubClient) ListUserProjects(user string) (ListUserProjectsResult, *h
 
 	var result ListUserProjectsResult
 
-	res, err := c.request(query, &result)
+	res, err := c.request(ctx, query, &result)
 	if err != nil {
 		return result, res, err
 	}
-	err = getErrorFromErrors(result.Errors)
+	err = GetErrorFromErrors(result.Errors)
 
 	return result, res, err
 }
@@ -65,7 +66,7 @@ type ListOrganizationProjectsResult struct {
 	} `json:"data"`
 }
 
-func (c *GitHubClient) ListOrganizationProjects(org string) (ListOrganizationProjectsResult, *http.Response, error) {
+func (c *GitHubClient) ListOrganizationProjects(ctx context.Context, org string) (ListOrganizationProjectsResult, *http.Response, error) {
 	query := fmt.Sprintf(`query{
 		organization(login:"%s") {
 			projectsV2(first:100){ nodes { id title } }
@@ -74,11 +75,11 @@ func (c *GitHubClient) ListOrganizationProjects(org string) (ListOrganizationPro
 
 	var result ListOrganizationProjectsResult
 
-	res, err := c.request(query, &result)
+	res, err := c.request(ctx, query, &result)
 	if err != nil {
 		return result, res, err
 	}
-	err = getErrorFromErrors(result.Errors)
+	err = GetErrorFromErrors(result.Errors)
 
 	return result, res, err
 }
@@ -101,7 +102,7 @@ type GetProjectFieldsResult struct {
 	} `json:"data"`
 }
 
-func (c *GitHubClient) GetProjectFields(id string) (GetProjectFieldsResult, *http.Response, error) {
+func (c *GitHubClient) GetProjectFields(ctx context.Context, id string) (GetProjectFieldsResult, *http.Response, error) {
 	query := fmt.Sprintf(`query{ node(id: "%s") {
 		... on ProjectV2 {
 			fields(first: 100) {nodes {
@@ -114,11 +115,11 @@ func (c *GitHubClient) GetProjectFields(id string) (GetProjectFieldsResult, *htt
 
 	var result GetProjectFieldsResult
 
-	res, err := c.request(query, &result)
+	res, err := c.request(ctx, query, &result)
 	if err != nil {
 		return result, res, err
 	}
-	err = getErrorFromErrors(result.Errors)
+	err = GetErrorFromErrors(result.Errors)
 
 	return result, res, err
 }
@@ -195,18 +196,32 @@ func (r GetProjectItemsResult) UnmarshallItems(v any) {
 	_ = json.Unmarshal(b, v)
 }
 
+// GetProjectItems retrieves every item of a project, following the items
+// pagination until the last page is reached. Archived items are returned
+// as well and can be told apart by their "isArchived" property.
+//
 // TODO: Add better way to access items
-func (c *GitHubClient) GetProjectItems(id string, fields []ProjectField) (GetProjectItemsResult, *http.Response, error) {
+func (c *GitHubClient) GetProjectItems(ctx context.Context, id string, fields []ProjectField) (GetProjectItemsResult, *http.Response, error) {
 	queryFields := ""
 	for i := 0; i < len(fields); i++ {
 		queryFields = fmt.Sprintf("%s %s", queryFields, fields[i].ToQuery())
 	}
 
-	query := fmt.Sprintf(`query{ node(id: "%s") { ... on ProjectV2 {
-		items(first: 100) {
+	var result GetProjectItemsResult
+	var res *http.Response
+	after := ""
+	for {
+		afterQuery := ""
+		if after != "" {
+			afterQuery = fmt.Sprintf(`, after: "%s"`, after)
+		}
+
+		query := fmt.Sprintf(`query{ node(id: "%s") { ... on ProjectV2 {
+		items(first: 100%s) {
 			pageInfo{startCursor endCursor hasNextPage hasPreviousPage} 
 			nodes{
 				id
+				isArchived
 				content{
 					__typename
 					... on Issue {comments(first:100) {nodes{body}}}
@@ -214,17 +229,29 @@ func (c *GitHubClient) GetProjectItems(id string, fields []ProjectField) (GetPro
 				%s
 			}
 		}
-	}}}}`, id, queryFields)
+	}}}}`, id, afterQuery, queryFields)
 
-	var result GetProjectItemsResult
+		var page GetProjectItemsResult
+		var err error
+		res, err = c.request(ctx, query, &page)
+		if err != nil {
+			return result, res, err
+		}
+		// err = GetErrorFromErrors(result.Errors)
 
-	res, err := c.request(query, &result)
-	if err != nil {
-		return result, res, err
+		if page.Errors != nil {
+			result.Errors = page.Errors
+		}
+		result.Data.Node.Items.Nodes = append(result.Data.Node.Items.Nodes, page.Data.Node.Items.Nodes...)
+		result.Data.Node.Items.PageInfo = page.Data.Node.Items.PageInfo
+
+		if !page.Data.Node.Items.PageInfo.HasNextPage || page.Data.Node.Items.PageInfo.EndCursor == "" {
+			break
+		}
+		after = page.Data.Node.Items.PageInfo.EndCursor
 	}
-	// err = getErrorFromErrors(result.Errors)
 
-	return result, res, err
+	return result, res, nil
 
 }
 
@@ -237,7 +264,7 @@ type UpdateProjectItemFieldResult struct {
 	} `json:"data"`
 }
 
-func (c *GitHubClient) UpdateProjectItemField(projectId, itemId, fieldId string, fieldType ProjectFieldType, value any) (UpdateProjectItemFieldResult, *http.Response, error) {
+func (c *GitHubClient) UpdateProjectItemField(ctx context.Context, projectId, itemId, fieldId string, fieldType ProjectFieldType, value any) (UpdateProjectItemFieldResult, *http.Response, error) {
 	var result UpdateProjectItemFieldResult
 	var valueQuery = ""
 	switch fieldType {
@@ -261,12 +288,44 @@ func (c *GitHubClient) UpdateProjectItemField(projectId, itemId, fieldId string,
 		}
 	}`, fieldId, itemId, projectId, uuid.NewString(), valueQuery)
 
-	res, err := c.request(query, &result)
+	res, err := c.request(ctx, query, &result)
 	if err != nil {
 		return result, res, err
 	}
-	err = getErrorFromErrors(result.Errors)
+	err = GetErrorFromErrors(result.Errors)
 
 	return result, res, err
 
 }
+
+type ClearProjectItemFieldResult struct {
+	Errors *[]Error `json:"errors"`
+	Data   struct {
+		Clear struct {
+			ClientMutId string `json:"clientMutationId"`
+		} `json:"clearProjectV2ItemFieldValue"`
+	} `json:"data"`
+}
+
+func (c *GitHubClient) ClearProjectItemField(ctx context.Context, projectId, itemId, fieldId string) (ClearProjectItemFieldResult, *http.Response, error) {
+	var result ClearProjectItemFieldResult
+
+	query := fmt.Sprintf(`mutation ClearProjectV2ItemFieldValue {
+		clearProjectV2ItemFieldValue(input: {
+			fieldId: "%s"
+			itemId: "%s"
+			projectId: "%s"
+			clientMutationId: "%s"
+		}) {
+			clientMutationId
+		}
+	}`, fieldId, itemId, projectId, uuid.NewString())
+
+	res, err := c.request(ctx, query, &result)
+	if err != nil {
+		return result, res, err
+	}
+	err = GetErrorFromErrors(result.Errors)
+
+	return result, res, err
+}
@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NormalizeText lowercases a text and replaces its punctuation and repeated
+// spaces by a single space, so formatting differences don't count when
+// comparing texts.
+func NormalizeText(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// Similarity scores how alike two texts are, from 0 (nothing in common) to
+// 1 (same normalized text). It is the highest of the edit distance ratio
+// and the shared words ratio, so both typos and reordered words score high.
+func Similarity(a, b string) float64 {
+	a, b = NormalizeText(a), NormalizeText(b)
+	if a == b {
+		return 1
+	}
+	if a == "" || b == "" {
+		return 0
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	editRatio := 1 - float64(levenshtein(ra, rb))/float64(max(len(ra), len(rb)))
+
+	return max(editRatio, wordsRatio(strings.Fields(a), strings.Fields(b)))
+}
+
+// levenshtein returns the edit distance between two texts.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// wordsRatio returns the ratio of distinct words shared by both texts.
+func wordsRatio(a, b []string) float64 {
+	setA := map[string]bool{}
+	for _, w := range a {
+		setA[w] = true
+	}
+	setB := map[string]bool{}
+	for _, w := range b {
+		setB[w] = true
+	}
+
+	shared := 0
+	for w := range setA {
+		if setB[w] {
+			shared++
+		}
+	}
+	total := len(setA) + len(setB) - shared
+	if total == 0 {
+		return 0
+	}
+
+	return float64(shared) / float64(total)
+}
@@ -0,0 +1,37 @@
+package helpers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{name: "same text", a: "Fix login", b: "Fix login", want: 1},
+		{name: "case and punctuation differences", a: "Fix: login page!", b: "fix login   page", want: 1},
+		{name: "nothing in common", a: "abc", b: "xyz", want: 0},
+		{name: "empty text", a: "", b: "Fix login", want: 0},
+		{name: "both empty", a: "", b: "", want: 1},
+		{name: "only punctuation", a: "!!", b: "Fix", want: 0},
+		{name: "reordered words", a: "login page fix", b: "fix login page", want: 1},
+		{name: "typo", a: "fix login", b: "fix logn", want: 1 - 1.0/9},
+		{name: "shared words", a: "deploy api server", b: "server api deploy now", want: 3.0 / 4},
+		{name: "edit ratio over shared words", a: "fix login page", b: "fix signup form", want: 1 - 9.0/15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Similarity(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if reverse := Similarity(tt.b, tt.a); math.Abs(reverse-got) > 1e-9 {
+				t.Errorf("Similarity(%q, %q) = %v, not symmetric with %v", tt.b, tt.a, reverse, got)
+			}
+		})
+	}
+}
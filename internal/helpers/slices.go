@@ -12,8 +12,8 @@ func FilterSlice[T any](slice []T, keep func(T) bool) []T {
 	return newSlice
 }
 
-func MapSlice[T any](slice []T, mapfn func(T) T) []T {
-	var newSlice []T
+func MapSlice[T, U any](slice []T, mapfn func(T) U) []U {
+	var newSlice []U
 
 	for _, item := range slice {
 		newSlice = append(newSlice, mapfn(item))
@@ -21,3 +21,19 @@ func MapSlice[T any](slice []T, mapfn func(T) T) []T {
 
 	return newSlice
 }
+
+// ChunkSlice splits a slice into chunks of at most size items, a size lower
+// than 1 keeps the whole slice in a single chunk.
+func ChunkSlice[T any](slice []T, size int) [][]T {
+	var chunks [][]T
+
+	for size > 0 && size < len(slice) {
+		chunks = append(chunks, slice[:size:size])
+		slice = slice[size:]
+	}
+	if len(slice) > 0 {
+		chunks = append(chunks, slice)
+	}
+
+	return chunks
+}
@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunkSlice(t *testing.T) {
+	tests := []struct {
+		name  string
+		slice []int
+		size  int
+		want  [][]int
+	}{
+		{name: "nil slice", slice: nil, size: 2, want: nil},
+		{name: "empty slice", slice: []int{}, size: 2, want: nil},
+		{name: "smaller than a chunk", slice: []int{1}, size: 2, want: [][]int{{1}}},
+		{name: "exactly a chunk", slice: []int{1, 2}, size: 2, want: [][]int{{1, 2}}},
+		{name: "last chunk is shorter", slice: []int{1, 2, 3, 4, 5}, size: 2, want: [][]int{{1, 2}, {3, 4}, {5}}},
+		{name: "chunks of one", slice: []int{1, 2, 3}, size: 1, want: [][]int{{1}, {2}, {3}}},
+		{name: "zero size keeps a single chunk", slice: []int{1, 2, 3}, size: 0, want: [][]int{{1, 2, 3}}},
+		{name: "negative size keeps a single chunk", slice: []int{1, 2, 3}, size: -1, want: [][]int{{1, 2, 3}}},
+		{name: "empty slice with zero size", slice: []int{}, size: 0, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChunkSlice(tt.slice, tt.size)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChunkSlice(%v, %d) = %v, want %v", tt.slice, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkSliceAppend(t *testing.T) {
+	// appending to a chunk must not overwrite the next one
+	chunks := ChunkSlice([]int{1, 2, 3, 4}, 2)
+	_ = append(chunks[0], 9)
+	if want := []int{3, 4}; !reflect.DeepEqual(chunks[1], want) {
+		t.Errorf("got second chunk %v after appending to the first, want %v", chunks[1], want)
+	}
+}
@@ -0,0 +1,227 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const NAMESPACE = "jira_tickets_from_gh"
+
+// services whose http requests are instrumented
+const (
+	SERVICE_GITHUB = "github"
+	SERVICE_JIRA   = "jira"
+)
+
+// transitions results
+const (
+	RESULT_SUCCESS = "success"
+	RESULT_FAILURE = "failure"
+)
+
+// http request error classes
+const (
+	ERROR_CLASS_CANCELED     = "canceled"
+	ERROR_CLASS_TIMEOUT      = "timeout"
+	ERROR_CLASS_NETWORK      = "network"
+	ERROR_CLASS_RATE_LIMITED = "rate_limited"
+	ERROR_CLASS_CLIENT       = "client"
+	ERROR_CLASS_SERVER       = "server"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	cycleDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "sync_cycle_duration_seconds",
+		Help:      "Duration of the project sync cycles.",
+		Buckets:   []float64{1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"project", "result"})
+	lastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "sync_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful project sync cycle.",
+	}, []string{"project"})
+	itemsSeen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "sync_items_seen",
+		Help:      "GitHub project items seen in the last project sync cycle.",
+	}, []string{"project"})
+	issuesCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "jira_issues_created_total",
+		Help:      "Jira issues created.",
+	}, []string{"project"})
+	transitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "jira_transitions_total",
+		Help:      "Jira issue transitions by result.",
+	}, []string{"project", "result"})
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_requests_total",
+		Help:      "Http requests sent to GitHub and Jira by response status code.",
+	}, []string{"service", "project", "code"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the http requests sent to GitHub and Jira.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "project"})
+	requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_request_errors_total",
+		Help:      "Failed http requests sent to GitHub and Jira by error class.",
+	}, []string{"service", "project", "class"})
+	githubRateLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "github_rate_limit_remaining",
+		Help:      "Remaining GitHub api rate limit as of the last GitHub response.",
+	}, []string{"project"})
+)
+
+func init() {
+	registry.MustRegister(
+		cycleDuration,
+		lastSuccess,
+		itemsSeen,
+		issuesCreated,
+		transitions,
+		requests,
+		requestDuration,
+		requestErrors,
+		githubRateLimit,
+	)
+}
+
+// Handler serves the metrics in the prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+type projectCtxKey struct{}
+
+// WithProject returns a context that labels the http requests sent with it
+// with the given sync project name.
+func WithProject(ctx context.Context, project string) context.Context {
+	return context.WithValue(ctx, projectCtxKey{}, project)
+}
+
+func getProject(ctx context.Context) string {
+	project, _ := ctx.Value(projectCtxKey{}).(string)
+	return project
+}
+
+// CycleSucceeded records a successful project sync cycle.
+func CycleSucceeded(project string, duration time.Duration) {
+	cycleDuration.WithLabelValues(project, RESULT_SUCCESS).Observe(duration.Seconds())
+	lastSuccess.WithLabelValues(project).SetToCurrentTime()
+}
+
+// CycleFailed records a failed project sync cycle.
+func CycleFailed(project string, duration time.Duration) {
+	cycleDuration.WithLabelValues(project, RESULT_FAILURE).Observe(duration.Seconds())
+}
+
+// ItemsSeen records the number of GitHub project items seen in a cycle.
+func ItemsSeen(project string, count int) {
+	itemsSeen.WithLabelValues(project).Set(float64(count))
+}
+
+// IssueCreated records a jira issue creation.
+func IssueCreated(project string) {
+	issuesCreated.WithLabelValues(project).Inc()
+}
+
+// Transitioned records a jira issue transition.
+func Transitioned(project string, err error) {
+	result := RESULT_SUCCESS
+	if err != nil {
+		result = RESULT_FAILURE
+	}
+	transitions.WithLabelValues(project, result).Inc()
+}
+
+// Transport is an http.RoundTripper that records the requests count,
+// latency and errors of a service. Requests are labeled with the project
+// set with WithProject in their context.
+type Transport struct {
+	Service string
+	Base    http.RoundTripper
+}
+
+// NewTransport instruments the base round tripper, http.DefaultTransport is
+// used if base is nil.
+func NewTransport(service string, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Service: service, Base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	project := getProject(req.Context())
+
+	start := time.Now()
+	res, err := t.Base.RoundTrip(req)
+	requestDuration.WithLabelValues(t.Service, project).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		requests.WithLabelValues(t.Service, project, "error").Inc()
+		requestErrors.WithLabelValues(t.Service, project, getErrorClass(err)).Inc()
+		return res, err
+	}
+
+	requests.WithLabelValues(t.Service, project, strconv.Itoa(res.StatusCode)).Inc()
+	class := getStatusErrorClass(res.StatusCode)
+	if t.Service == SERVICE_GITHUB {
+		remaining, err := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining"))
+		if err == nil {
+			githubRateLimit.WithLabelValues(project).Set(float64(remaining))
+		}
+		// github answers with a 403 once the primary rate limit is spent
+		if err == nil && remaining == 0 && res.StatusCode == http.StatusForbidden {
+			class = ERROR_CLASS_RATE_LIMITED
+		}
+	}
+	if class != "" {
+		requestErrors.WithLabelValues(t.Service, project, class).Inc()
+	}
+
+	return res, nil
+}
+
+func getErrorClass(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ERROR_CLASS_CANCELED
+	case errors.Is(err, context.DeadlineExceeded):
+		return ERROR_CLASS_TIMEOUT
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ERROR_CLASS_TIMEOUT
+	default:
+		return ERROR_CLASS_NETWORK
+	}
+}
+
+func getStatusErrorClass(code int) string {
+	switch {
+	case code == http.StatusTooManyRequests:
+		return ERROR_CLASS_RATE_LIMITED
+	case code >= 500:
+		return ERROR_CLASS_SERVER
+	case code >= 400:
+		return ERROR_CLASS_CLIENT
+	default:
+		return ""
+	}
+}
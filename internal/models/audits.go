@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type AuditAction string
+
+// outbound mutations recorded in the audit log
+const (
+	AUDIT_ACTION_JIRA_CREATE          AuditAction = "jira_create"
+	AUDIT_ACTION_JIRA_TRANSITION      AuditAction = "jira_transition"
+	AUDIT_ACTION_JIRA_FIELD_UPDATE    AuditAction = "jira_field_update"
+	AUDIT_ACTION_JIRA_COMMENT         AuditAction = "jira_comment"
+	AUDIT_ACTION_JIRA_PROPERTY_SET    AuditAction = "jira_property_set"
+	AUDIT_ACTION_JIRA_PROPERTY_DELETE AuditAction = "jira_property_delete"
+	AUDIT_ACTION_GITHUB_FIELD_WRITE   AuditAction = "github_field_write"
+	AUDIT_ACTION_GITHUB_FIELD_CLEAR   AuditAction = "github_field_clear"
+)
+
+type AuditResult string
+
+const (
+	AUDIT_RESULT_SUCCESS AuditResult = "success"
+	AUDIT_RESULT_FAILURE AuditResult = "failure"
+)
+
+// DEFAULT_AUDIT_LIMIT is the number of audit entries listed when no limit
+// is given.
+const DEFAULT_AUDIT_LIMIT = 100
+
+// AuditEntry is the record of an outbound mutation against Jira or GitHub.
+type AuditEntry struct {
+	ID              int64
+	CreatedAt       time.Time
+	GitHubProjectID string
+	GitHubID        string
+	JiraKey         *string
+	Action          AuditAction
+	Summary         string // human readable description of the request
+	Result          AuditResult
+	Error           *string
+}
+
+// AuditFilter narrows the audit entries to be listed, zero valued fields
+// are not taken into account.
+type AuditFilter struct {
+	GitHubProjectID string
+	GitHubID        string
+	JiraKey         string
+	Action          AuditAction
+	Result          AuditResult
+	Since           time.Time
+	Limit           int // defaults to DEFAULT_AUDIT_LIMIT
+}
+
+type Audits struct {
+	db *sql.DB
+}
+
+// Add records an outbound mutation, its result is taken from the mutation
+// error.
+func (service *Audits) Add(projectId, id, jiraKey string, action AuditAction, summary string, mutationErr error) (*AuditEntry, error) {
+	entry := new(AuditEntry)
+	entry.CreatedAt = time.Now().UTC()
+	entry.GitHubProjectID = projectId
+	entry.GitHubID = id
+	if jiraKey != "" {
+		entry.JiraKey = &jiraKey
+	}
+	entry.Action = action
+	entry.Summary = summary
+	entry.Result = AUDIT_RESULT_SUCCESS
+	if mutationErr != nil {
+		msg := mutationErr.Error()
+		entry.Result = AUDIT_RESULT_FAILURE
+		entry.Error = &msg
+	}
+
+	stmt := `INSERT INTO audit(
+			createdAt,
+			projectId,
+			itemId,
+			jiraKey,
+			action,
+			summary,
+			result,
+			error
+		) values(?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := service.db.Exec(
+		stmt,
+		entry.CreatedAt,
+		entry.GitHubProjectID,
+		entry.GitHubID,
+		entry.JiraKey,
+		entry.Action,
+		entry.Summary,
+		entry.Result,
+		entry.Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if entry.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// List retrieves the audit entries that match the filter, newest first.
+func (service *Audits) List(filter AuditFilter) ([]*AuditEntry, error) {
+	where := []string{}
+	args := []any{}
+	if filter.GitHubProjectID != "" {
+		where = append(where, "projectId = ?")
+		args = append(args, filter.GitHubProjectID)
+	}
+	if filter.GitHubID != "" {
+		where = append(where, "itemId = ?")
+		args = append(args, filter.GitHubID)
+	}
+	if filter.JiraKey != "" {
+		where = append(where, "jiraKey = ?")
+		args = append(args, filter.JiraKey)
+	}
+	if filter.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Result != "" {
+		where = append(where, "result = ?")
+		args = append(args, filter.Result)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "createdAt >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DEFAULT_AUDIT_LIMIT
+	}
+	args = append(args, limit)
+
+	stmt := `SELECT
+		id,
+		createdAt,
+		projectId,
+		itemId,
+		jiraKey,
+		action,
+		summary,
+		result,
+		error
+	FROM audit`
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += " ORDER BY id DESC LIMIT ?"
+
+	rows, err := service.db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*AuditEntry{}
+	for rows.Next() {
+		entry := new(AuditEntry)
+		err = rows.Scan(
+			&entry.ID,
+			&entry.CreatedAt,
+			&entry.GitHubProjectID,
+			&entry.GitHubID,
+			&entry.JiraKey,
+			&entry.Action,
+			&entry.Summary,
+			&entry.Result,
+			&entry.Error,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
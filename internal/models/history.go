@@ -0,0 +1,153 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// IssueStatusChange is an observed change of the status of a GitHub project
+// item, it holds the item attributes as of the change.
+type IssueStatusChange struct {
+	ID              int64
+	GitHubProjectID string
+	GitHubID        string
+	Title           string
+	Status          string // GitHub status name, not restricted to the synced ones
+	JiraIssueType   *string
+	Repository      *string
+	Assignees       []string
+	ChangedAt       time.Time
+}
+
+type IssueHistory struct {
+	db *sql.DB
+}
+
+// RecordMany records the status of the given items whose status changed
+// since the last time they were recorded (or that were never recorded).
+// Items without a status are skipped.
+func (service *IssueHistory) RecordMany(projectId string, issues []RemoteIssue) (int, error) {
+	stmt := `SELECT h.itemId, h.status
+	FROM issue_history h
+	WHERE h.projectId = ?
+	AND h.id = (SELECT MAX(id) FROM issue_history WHERE projectId = h.projectId AND itemId = h.itemId)`
+	rows, err := service.db.Query(stmt, projectId)
+	if err != nil {
+		return 0, err
+	}
+	lastStatuses := map[string]string{}
+	for rows.Next() {
+		var id, status string
+		if err := rows.Scan(&id, &status); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		lastStatuses[id] = status
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	changedAt := time.Now().UTC()
+	tx, err := service.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	stmt = `INSERT INTO issue_history(
+			projectId,
+			itemId,
+			title,
+			status,
+			jiraIssueType,
+			repository,
+			assignees,
+			changedAt
+		) values(?, ?, ?, ?, ?, ?, ?, ?)`
+	recorded := 0
+	for _, ri := range issues {
+		if ri.Status == nil {
+			continue
+		}
+		if last, ok := lastStatuses[ri.ID]; ok && last == ri.Status.Name {
+			continue
+		}
+
+		is := ri.ToIssue(projectId)
+		_, err := tx.Exec(
+			stmt,
+			projectId,
+			is.GitHubID,
+			is.Title,
+			ri.Status.Name,
+			is.JiraIssueType,
+			is.Repository,
+			strings.Join(is.Assignees, ";"),
+			changedAt,
+		)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		recorded++
+	}
+
+	return recorded, tx.Commit()
+}
+
+// HISTORY_COLUMNS are the issue_history table columns scanned by
+// scanStatusChange.
+const HISTORY_COLUMNS = `id,
+		projectId,
+		itemId,
+		title,
+		status,
+		jiraIssueType,
+		repository,
+		assignees,
+		changedAt`
+
+// scanStatusChange scans a row holding the HISTORY_COLUMNS.
+func scanStatusChange(row rowScanner) (*IssueStatusChange, error) {
+	change := new(IssueStatusChange)
+	var assigneesStr string
+	err := row.Scan(
+		&change.ID,
+		&change.GitHubProjectID,
+		&change.GitHubID,
+		&change.Title,
+		&change.Status,
+		&change.JiraIssueType,
+		&change.Repository,
+		&assigneesStr,
+		&change.ChangedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	change.Assignees = []string{}
+	if assigneesStr != "" {
+		change.Assignees = strings.Split(assigneesStr, ";")
+	}
+	return change, nil
+}
+
+// GetAll retrieves the status changes of a project sorted by item and time.
+func (service *IssueHistory) GetAll(projectId string) ([]*IssueStatusChange, error) {
+	stmt := `SELECT ` + HISTORY_COLUMNS + `
+	FROM issue_history
+	WHERE projectId = ?
+	ORDER BY itemId, changedAt, id`
+	return queryAll(service.db, scanStatusChange, stmt, projectId)
+}
+
+// GetByItem retrieves the status changes of a project item sorted by time.
+func (service *IssueHistory) GetByItem(projectId, itemId string) ([]*IssueStatusChange, error) {
+	stmt := `SELECT ` + HISTORY_COLUMNS + `
+	FROM issue_history
+	WHERE projectId = ?
+	AND itemId = ?
+	ORDER BY changedAt, id`
+	return queryAll(service.db, scanStatusChange, stmt, projectId, itemId)
+}
@@ -1,16 +1,19 @@
 package models
 
 import (
+	"database/sql"
 	"errors"
-	"fmt"
 	"regexp"
 	"slices"
 	"strings"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/helpers"
 )
 
 type RemoteIssue struct {
-	ID       string `json:"id"`
-	Estimate struct {
+	ID         string `json:"id"`
+	IsArchived bool   `json:"isArchived"`
+	Estimate   struct {
 		Num *int `json:"number"`
 	} `json:"estimate"`
 	JiraIssueType *struct {
@@ -65,7 +68,7 @@ func (ri RemoteIssue) ToIssue(projectId string) *Issue {
 }
 
 type Issues struct {
-	models *Models
+	db *sql.DB
 }
 
 func (service *Issues) Upsert(projectId, id, title string, status *IssueStatus, jiraUrl, jiraIssueType, repo *string, estimate *int, assignees *[]string) (*Issue, error) {
@@ -85,7 +88,7 @@ func (service *Issues) Upsert(projectId, id, title string, status *IssueStatus,
 			assignees,
 			repository
 		) values(?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := service.models.db.Exec(
+	_, err := service.db.Exec(
 		stmt,
 		projectId,
 		id,
@@ -141,7 +144,7 @@ func (service *Issues) UpsertMany(projectId string, issues []RemoteIssue) ([]*Is
 		resultIssue.Estimate = issue.Estimate.Num
 		if issue.Status == nil {
 			resultIssue.Status = nil
-		} else if issue.Status.Name == string(STATUS_WIP) || issue.Status.Name == string(STATUS_DONE) || issue.Status.Name == string(STATUS_TODO) {
+		} else if IssueStatus(issue.Status.Name).IsKnown() {
 			resultIssue.Status = (*IssueStatus)(&issue.Status.Name)
 		} else {
 			resultIssue.Status = nil
@@ -149,7 +152,7 @@ func (service *Issues) UpsertMany(projectId string, issues []RemoteIssue) ([]*Is
 		resultIssues = append(resultIssues, resultIssue)
 	}
 
-	tx, err := service.models.db.Begin()
+	tx, err := service.db.Begin()
 	if err != nil {
 		return nil, err
 	}
@@ -168,6 +171,7 @@ func (service *Issues) UpsertMany(projectId string, issues []RemoteIssue) ([]*Is
 		assigneesStr := strings.Join(v.Assignees, ";")
 		_, err := tx.Exec(stmt, v.GitHubProjectID, v.GitHubID, v.JiraURL, v.JiraIssueType, v.Title, v.Estimate, v.Status, assigneesStr, v.Repository)
 		if err != nil {
+			tx.Rollback()
 			return nil, err
 		}
 	}
@@ -182,7 +186,7 @@ func (service *Issues) UpsertMany(projectId string, issues []RemoteIssue) ([]*Is
 func (service *Issues) UpdateUrl(projectId, id, jiraUrl string) error {
 	stmt := `UPDATE issues SET jiraUrl = ?
 		WHERE projectId = ? AND id = ?`
-	_, err := service.models.db.Exec(
+	_, err := service.db.Exec(
 		stmt,
 		jiraUrl,
 		projectId,
@@ -191,40 +195,38 @@ func (service *Issues) UpdateUrl(projectId, id, jiraUrl string) error {
 	return err
 }
 
-// Get retrieves a project issue, if no issue found *Issue will be nil
-func (p *Issues) Get(githubProjectId, githubId string) (*Issue, error) {
-	if githubProjectId == "" {
-		return nil, errors.New(`please provide a value for "githubProjectId"`)
-	}
-	if githubId == "" {
-		return nil, errors.New(`please provide a value for "githubId"`)
-	}
-
-	stmt := fmt.Sprintf(`SELECT
+// ClearUrl removes the jira url of an issue.
+func (service *Issues) ClearUrl(projectId, id string) error {
+	stmt := `UPDATE issues SET jiraUrl = NULL
+		WHERE projectId = ? AND id = ?`
+	_, err := service.db.Exec(
+		stmt,
 		projectId,
 		id,
+	)
+	return err
+}
+
+// ISSUE_COLUMNS are the issues table columns scanned by scanIssue.
+const ISSUE_COLUMNS = `projectId,
+		id,
 		jiraUrl,
 		jiraIssueType,
 		title,
 		estimate,
 		status,
 		assignees,
-		repository
-	FROM issues
-	WHERE id = "%s"
-	AND projectId = "%s"
-	`, githubId, githubProjectId)
-	rows, err := p.models.db.Query(stmt)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+		repository`
+
+// jiraUrlRegex matches the jira issues urls written by the sync.
+var jiraUrlRegex = regexp.MustCompile(`^https\:\/\/[a-zA-Z0-9]*\.atlassian\.net\/browse\/.*`)
+
+// scanIssue scans a row holding the ISSUE_COLUMNS.
+func scanIssue(row rowScanner) (*Issue, error) {
 	issue := new(Issue)
 	var assigneesStr *string
-	if !rows.Next() {
-		return nil, nil
-	}
-	err = rows.Scan(
+
+	err := row.Scan(
 		&issue.GitHubProjectID,
 		&issue.GitHubID,
 		&issue.JiraURL,
@@ -239,69 +241,43 @@ func (p *Issues) Get(githubProjectId, githubId string) (*Issue, error) {
 		return nil, err
 	}
 	assignees := []string{}
-	if assigneesStr != nil {
+	if assigneesStr != nil && *assigneesStr != "" {
 		assignees = strings.Split(*assigneesStr, ";")
 	}
 	issue.Assignees = assignees
-	// uncomment when models is avaialbe within an issue
-	// issue.models = p.models
 	return issue, nil
 }
 
-// GetAll retrieves all project issues, if no issue found *Issue will be nil.
-func (p *Issues) GetAll(githubProjectId string) ([]*Issue, error) {
+// Get retrieves a project issue, if no issue found *Issue will be nil
+func (p *Issues) Get(githubProjectId, githubId string) (*Issue, error) {
 	if githubProjectId == "" {
 		return nil, errors.New(`please provide a value for "githubProjectId"`)
 	}
+	if githubId == "" {
+		return nil, errors.New(`please provide a value for "githubId"`)
+	}
 
-	stmt := fmt.Sprintf(`SELECT
-		projectId,
-		id,
-		jiraUrl,
-		jiraIssueType,
-		title,
-		estimate,
-		status,
-		assignees,
-		repository
+	stmt := `SELECT ` + ISSUE_COLUMNS + `
 	FROM issues
-	WHERE projectId = "%s"
-	`, githubProjectId)
-	rows, err := p.models.db.Query(stmt)
-	if err != nil {
+	WHERE id = ?
+	AND projectId = ?`
+	issue, ok, err := queryOne(p.db, scanIssue, stmt, githubId, githubProjectId)
+	if err != nil || !ok {
 		return nil, err
 	}
-	defer rows.Close()
-	issues := []*Issue{}
-	for rows.Next() {
-		issue := new(Issue)
-		var assigneesStr *string
-
-		err = rows.Scan(
-			&issue.GitHubProjectID,
-			&issue.GitHubID,
-			&issue.JiraURL,
-			&issue.JiraIssueType,
-			&issue.Title,
-			&issue.Estimate,
-			&issue.Status,
-			&assigneesStr,
-			&issue.Repository,
-		)
-		if err != nil {
-			return nil, err
-		}
-		assignees := []string{}
-		if assigneesStr != nil && *assigneesStr != "" {
-			assignees = strings.Split(*assigneesStr, ";")
-		}
-		issue.Assignees = assignees
-		// uncomment when models is avaialbe within an issue
-		// issue.models = p.models
-		issues = append(issues, issue)
+	return issue, nil
+}
+
+// GetAll retrieves all project issues, if no issue found *Issue will be nil.
+func (p *Issues) GetAll(githubProjectId string) ([]*Issue, error) {
+	if githubProjectId == "" {
+		return nil, errors.New(`please provide a value for "githubProjectId"`)
 	}
 
-	return issues, nil
+	stmt := `SELECT ` + ISSUE_COLUMNS + `
+	FROM issues
+	WHERE projectId = ?`
+	return queryAll(p.db, scanIssue, stmt, githubProjectId)
 }
 
 type Diff struct {
@@ -316,7 +292,9 @@ func (s *Issues) GetThoseWithDiff(projectId string, issues []RemoteIssue) (diff
 		if err != nil {
 			return nil, err
 		}
-		if localIssue == nil {
+		// issues without a known status on either side have nothing to
+		// transition from or to
+		if localIssue == nil || localIssue.Status == nil || remoteIssue.Status == nil {
 			continue
 		}
 		if *localIssue.Status == STATUS_DONE {
@@ -335,9 +313,7 @@ func (s *Issues) GetThoseWithDiff(projectId string, issues []RemoteIssue) (diff
 			if remoteIssue.JiraIssueType != nil {
 				issue.JiraIssueType = &remoteIssue.JiraIssueType.Name
 			}
-			if remoteIssue.Status != nil {
-				issue.Status = (*IssueStatus)(&remoteIssue.Status.Name)
-			}
+			issue.Status = (*IssueStatus)(&remoteIssue.Status.Name)
 			issue.Estimate = remoteIssue.Estimate.Num
 			issue.Repository = remoteIssue.Repository.Repository.Text
 			issue.Assignees = assignees
@@ -358,65 +334,18 @@ func (p *Issues) GetWithoutUrl(githubProjectId string) ([]*Issue, error) {
 		return nil, errors.New(`please provide a value for "githubProjectId"`)
 	}
 
-	stmt := fmt.Sprintf(`SELECT
-		projectId,
-		id,
-		jiraUrl,
-		jiraIssueType,
-		title,
-		estimate,
-		status,
-		assignees,
-		repository
+	stmt := `SELECT ` + ISSUE_COLUMNS + `
 	FROM issues
-	WHERE projectId = "%s"
-	AND jiraUrl IS NULL
-	`, githubProjectId)
-	rows, err := p.models.db.Query(stmt)
+	WHERE projectId = ?
+	AND jiraUrl IS NULL`
+	issues, err := queryAll(p.db, scanIssue, stmt, githubProjectId)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	issues := []*Issue{}
-	for rows.Next() {
-		issue := new(Issue)
-		var assigneesStr *string
-
-		err = rows.Scan(
-			&issue.GitHubProjectID,
-			&issue.GitHubID,
-			&issue.JiraURL,
-			&issue.JiraIssueType,
-			&issue.Title,
-			&issue.Estimate,
-			&issue.Status,
-			&assigneesStr,
-			&issue.Repository,
-		)
-		if err != nil {
-			return nil, err
-		}
-		assignees := []string{}
-		if assigneesStr != nil && *assigneesStr != "" {
-			assignees = strings.Split(*assigneesStr, ";")
-		}
-		issue.Assignees = assignees
-
-		match, err := regexp.MatchString(`^https\:\/\/[a-zA-Z0-9]*\.atlassian\.net\/browse\/.*`, *issue.JiraURL)
-		if err != nil {
-			return nil, err
-		}
-
-		if match {
-			continue
-		}
-
-		// uncomment when models is avaialbe within an issue
-		// issue.models = p.models
-		issues = append(issues, issue)
-	}
 
-	return issues, nil
+	return helpers.FilterSlice(issues, func(is *Issue) bool {
+		return is.JiraURL == nil || !jiraUrlRegex.MatchString(*is.JiraURL)
+	}), nil
 }
 
 // GetWithoutUrl retrieves project issues whoose jira url field is not nil, if no issues are found []*Issue will be nil.
@@ -425,99 +354,49 @@ func (p *Issues) GetWithUrl(githubProjectId string) ([]*Issue, error) {
 		return nil, errors.New(`please provide a value for "githubProjectId"`)
 	}
 
-	stmt := fmt.Sprintf(`SELECT
-		projectId,
-		id,
-		jiraUrl,
-		jiraIssueType,
-		title,
-		estimate,
-		status,
-		assignees,
-		repository
+	stmt := `SELECT ` + ISSUE_COLUMNS + `
 	FROM issues
-	WHERE projectId = "%s"
-	AND jiraUrl IS NOT NULL
-	`, githubProjectId)
-	rows, err := p.models.db.Query(stmt)
+	WHERE projectId = ?
+	AND jiraUrl IS NOT NULL`
+	issues, err := queryAll(p.db, scanIssue, stmt, githubProjectId)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	issues := []*Issue{}
-	for rows.Next() {
-		issue := new(Issue)
-		var assigneesStr *string
-
-		err = rows.Scan(
-			&issue.GitHubProjectID,
-			&issue.GitHubID,
-			&issue.JiraURL,
-			&issue.JiraIssueType,
-			&issue.Title,
-			&issue.Estimate,
-			&issue.Status,
-			&assigneesStr,
-			&issue.Repository,
-		)
-		if err != nil {
-			return nil, err
-		}
-		assignees := []string{}
-		if assigneesStr != nil && *assigneesStr != "" {
-			assignees = strings.Split(*assigneesStr, ";")
-		}
-		issue.Assignees = assignees
-		match, err := regexp.MatchString(`^https\:\/\/[a-zA-Z0-9]*\.atlassian\.net\/browse\/.*`, *issue.JiraURL)
-		if err != nil {
-			return nil, err
-		}
-
-		if !match {
-			continue
-		}
-		// uncomment when models is avaialbe within an issue
-		// issue.models = p.models
-		issues = append(issues, issue)
-	}
 
-	return issues, nil
+	return helpers.FilterSlice(issues, func(is *Issue) bool {
+		return jiraUrlRegex.MatchString(*is.JiraURL)
+	}), nil
 }
 
 // FindThoseThatExist takes a list of issues ids and returns those that does exists.
+// Ids are queried in chunks of QUERY_IN_CHUNK_SIZE.
 func (p *Issues) FindThoseThatExist(githubProjectId string, ids []string) ([]string, error) {
-	newIds := slices.Clone(ids)
 	if githubProjectId == "" {
 		return []string{}, errors.New(`please provide a value for "githubProjectId"`)
 	}
 
-	for i := 0; i < len(newIds); i++ {
-		newIds[i] = fmt.Sprintf(`"%s"`, newIds[i])
-	}
-	idsQuery := strings.Join(newIds, ",")
-	// query to select those ids that exist
-	stmt := fmt.Sprintf(`SELECT
-		id
-	FROM issues
-	WHERE projectId = "%s"
-	AND id IN (%s)
-	`, githubProjectId, idsQuery)
-	rows, err := p.models.db.Query(stmt)
-	if err != nil {
-		return []string{}, err
-	}
-	defer rows.Close()
 	idsThatExist := []string{}
-	for rows.Next() {
-		id := ""
+	for _, chunk := range helpers.ChunkSlice(ids, QUERY_IN_CHUNK_SIZE) {
+		// query to select those ids that exist
+		stmt := `SELECT
+			id
+		FROM issues
+		WHERE projectId = ?
+		AND id IN (` + placeholders(len(chunk)) + `)`
+		args := []any{githubProjectId}
+		for _, id := range chunk {
+			args = append(args, id)
+		}
 
-		err = rows.Scan(
-			&id,
-		)
+		found, err := queryAll(p.db, func(row rowScanner) (string, error) {
+			var id string
+			err := row.Scan(&id)
+			return id, err
+		}, stmt, args...)
 		if err != nil {
 			return []string{}, err
 		}
-		idsThatExist = append(idsThatExist, id)
+		idsThatExist = append(idsThatExist, found...)
 	}
 
 	return idsThatExist, nil
@@ -547,6 +426,18 @@ func (p *Issues) FindThoseThatDoesntExist(githubProjectId string, ids []string)
 	return idsThatDoesntExist, nil
 }
 
+// GetThoseNotIn takes a list of issues ids and returns the stored issues whose id is not within the list.
+func (p *Issues) GetThoseNotIn(githubProjectId string, ids []string) ([]*Issue, error) {
+	issues, err := p.GetAll(githubProjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	return helpers.FilterSlice(issues, func(is *Issue) bool {
+		return !slices.Contains(ids, is.GitHubID)
+	}), nil
+}
+
 type IssueStatus string
 
 const (
@@ -555,6 +446,11 @@ const (
 	STATUS_DONE IssueStatus = "Done"
 )
 
+// IsKnown reports whether the status is one the sync maps to jira.
+func (s IssueStatus) IsKnown() bool {
+	return s == STATUS_TODO || s == STATUS_WIP || s == STATUS_DONE
+}
+
 type Issue struct {
 	GitHubProjectID string
 	GitHubID        string
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestGetThoseWithDiff(t *testing.T) {
+	m, err := InitializeInMemory(DEFAULT_BACKEND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	todo, custom := STATUS_TODO, IssueStatus("Blocked")
+	stored := map[string]*IssueStatus{"todo": &todo, "no-status": nil, "custom": &custom, "todo-no-remote-status": &todo}
+	for id, status := range stored {
+		if _, err := m.Issues.Upsert("P", id, id, status, nil, nil, nil, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	remote := func(id string, status *string) RemoteIssue {
+		ri := RemoteIssue{ID: id}
+		ri.Title.Text = id
+		if status != nil {
+			ri.Status = &struct {
+				Name     string `json:"name"`
+				OptionID string `json:"optionId"`
+			}{Name: *status}
+		}
+		return ri
+	}
+	wip := string(STATUS_WIP)
+	diff, err := m.Issues.GetThoseWithDiff("P", []RemoteIssue{
+		remote("todo", &wip),
+		remote("no-status", &wip),
+		remote("custom", &wip),
+		remote("todo-no-remote-status", nil),
+		remote("unknown", &wip),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diff) != 1 {
+		t.Fatalf("got %d diffs, want only the one of the todo issue", len(diff))
+	}
+	if diff[0].Issue.GitHubID != "todo" || *diff[0].PrevStatus != STATUS_TODO || diff[0].NewStatus != STATUS_WIP {
+		t.Errorf("got diff %+v, want todo from %q to %q", diff[0], STATUS_TODO, STATUS_WIP)
+	}
+}
+
+func TestIssueStatusIsKnown(t *testing.T) {
+	for _, status := range []IssueStatus{STATUS_TODO, STATUS_WIP, STATUS_DONE} {
+		if !status.IsKnown() {
+			t.Errorf("got %q unknown, want it known", status)
+		}
+	}
+	for _, status := range []IssueStatus{"", "Blocked", "done"} {
+		if status.IsKnown() {
+			t.Errorf("got %q known, want it unknown", status)
+		}
+	}
+}
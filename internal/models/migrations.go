@@ -0,0 +1,256 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrNotMigrated is returned when a database is opened read-only
+// before its migrations are applied.
+var ErrNotMigrated = errors.New("database is not migrated")
+
+// migration is a schema change, migrations are applied in version order and
+// every applied version is recorded in the schema_version table. Versions
+// must never be reordered nor changed once released, new schema changes are
+// appended as a new migration.
+type migration struct {
+	version     int
+	description string
+	stmts       []string
+}
+
+// MigrationStatus tells whether a migration was applied to the database.
+type MigrationStatus struct {
+	Version     int
+	Description string
+	AppliedAt   *time.Time // nil if it's pending
+}
+
+// migrations holds every schema change. The ones released before the
+// migrations system use "IF NOT EXISTS" so databases created by previous
+// versions are adopted without changes.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create projects and issues tables",
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS projects (
+				id string not null,
+				FID_jiraUrl string not null,
+				FID_jiraIssueType string not null,
+				FID_title string not null,
+				FID_estimate string not null,
+				FID_status string not null,
+				FID_assignees string not null,
+				FID_repository string not null,
+				primary key (id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS issues (
+				projectId	string not null,
+				id		string not null,
+				title		string not null,
+				jiraUrl		string,
+				jiraIssueType	string,
+				estimate	int,
+				status		string,
+				assignees	string,
+				repository	string,
+				primary key (projectId, id)
+			)`,
+		},
+	},
+	{
+		version:     2,
+		description: "create tombstones table",
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS tombstones (
+				projectId	string not null,
+				id		string not null,
+				title		string not null,
+				jiraUrl		string,
+				action		string not null,
+				removedAt	datetime not null,
+				primary key (projectId, id)
+			)`,
+		},
+	},
+	{
+		version:     3,
+		description: "create operations table",
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS operations (
+				id		string not null,
+				projectId	string not null,
+				itemId		string not null,
+				kind		string not null,
+				status		string not null,
+				completedSteps	string not null,
+				jiraKey		string,
+				payload		string not null,
+				createdAt	datetime not null,
+				updatedAt	datetime not null,
+				primary key (id)
+			)`,
+		},
+	},
+	{
+		version:     4,
+		description: "create audit table",
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS audit (
+				id		integer primary key autoincrement,
+				createdAt	datetime not null,
+				projectId	string not null,
+				itemId		string not null,
+				jiraKey		string,
+				action		string not null,
+				summary		string not null,
+				result		string not null,
+				error		string
+			)`,
+		},
+	},
+	{
+		version:     5,
+		description: "create issue_history table",
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS issue_history (
+				id		integer primary key autoincrement,
+				projectId	string not null,
+				itemId		string not null,
+				title		string not null,
+				status		string not null,
+				jiraIssueType	string,
+				repository	string,
+				assignees	string not null,
+				changedAt	datetime not null
+			)`,
+			`CREATE INDEX IF NOT EXISTS issue_history_item ON issue_history (projectId, itemId)`,
+		},
+	},
+}
+
+// migrate applies the pending migrations, each one within its own
+// transaction. It fails if the database was migrated by a newer version.
+func migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version		integer not null,
+		description	string not null,
+		appliedAt	datetime not null,
+		primary key (version)
+	)`)
+	if err != nil {
+		return err
+	}
+
+	applied, err := getAppliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	latest := migrations[len(migrations)-1].version
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("database schema version %d is newer than the latest supported one (%d), please upgrade", version, latest)
+		}
+	}
+
+	for _, mig := range migrations {
+		if _, ok := applied[mig.version]; ok {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, stmt := range mig.stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d (%s) failed: %w", mig.version, mig.description, err)
+			}
+		}
+		_, err = tx.Exec(
+			`INSERT INTO schema_version(version, description, appliedAt) values(?, ?, ?)`,
+			mig.version,
+			mig.description,
+			time.Now().UTC(),
+		)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getAppliedMigrations retrieves the applied migrations times by version.
+func getAppliedMigrations(db *sql.DB) (map[int]time.Time, error) {
+	applied := map[int]time.Time{}
+
+	var tables int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`).Scan(&tables)
+	if err != nil {
+		return nil, err
+	}
+	if tables == 0 {
+		return applied, nil
+	}
+
+	rows, err := db.Query(`SELECT version, appliedAt FROM schema_version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// GetMigrationsStatus retrieves the status of every known migration in the
+// database at the given path, without applying any of them nor creating
+// the database.
+func GetMigrationsStatus(backend Backend, path string) ([]MigrationStatus, error) {
+	applied := map[int]time.Time{}
+
+	if _, err := os.Stat(path); err == nil {
+		db, err := openBackend(backend, path, true)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+
+		applied, err = getAppliedMigrations(db)
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	result := []MigrationStatus{}
+	for _, mig := range migrations {
+		status := MigrationStatus{Version: mig.version, Description: mig.description}
+		if appliedAt, ok := applied[mig.version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		result = append(result, status)
+	}
+
+	return result, nil
+}
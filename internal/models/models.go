@@ -2,66 +2,126 @@ package models
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 
-	_ "github.com/mattn/go-sqlite3"
+	_ "modernc.org/sqlite"
 )
 
+// DEFAULT_DB_PATH is the database location used when none is configured.
+const DEFAULT_DB_PATH = "./data/storage.db"
+
+// Models holds the repositories of the local storage, a storage backend
+// provides every one of them.
 type Models struct {
-	db       *sql.DB
-	Projects Projects
-	Issues   Issues
+	Projects   ProjectsRepository
+	Issues     IssuesRepository
+	Tombstones TombstonesRepository
+	Operations OperationsRepository
+	Audits     AuditsRepository
+	History    IssueHistoryRepository
+
+	close func() error
 }
 
 func (m *Models) Close() error {
-	return m.db.Close()
+	return m.close()
+}
+
+// Initialize opens the database at the given path with the given backend,
+// creating it and its directory if needed, and applies the pending schema
+// migrations.
+func Initialize(backend Backend, path string) (*Models, error) {
+	db, err := open(backend, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return newSqlModels(db), nil
 }
 
-func Initialize() (*Models, error) {
-	models := new(Models)
+// OpenReadOnly opens the existing database at the given path without
+// creating it nor applying migrations, none of its repositories can write.
+// It fails with ErrNotMigrated when the database doesn't exist yet or
+// some migrations are pending.
+func OpenReadOnly(backend Backend, path string) (*Models, error) {
+	if path == "" {
+		path = DEFAULT_DB_PATH
+	}
 
-	if err := os.MkdirAll("./data", os.ModePerm); err != nil {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf(`database "%s" doesn't exist: %w`, path, ErrNotMigrated)
+	} else if err != nil {
 		return nil, err
 	}
 
-	db, err := sql.Open("sqlite3", "./data/storage.db")
+	db, err := openBackend(backend, path, true)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS projects (
-		id string not null,
-		FID_jiraUrl string not null,
-		FID_jiraIssueType string not null,
-		FID_title string not null,
-		FID_estimate string not null,
-		FID_status string not null,
-		FID_assignees string not null,
-		FID_repository string not null,
-		primary key (id)
-	)`)
+	applied, err := getAppliedMigrations(db)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
+	pending := 0
+	for _, mig := range migrations {
+		if _, ok := applied[mig.version]; !ok {
+			pending++
+		}
+	}
+	if pending > 0 {
+		db.Close()
+		return nil, fmt.Errorf("%w, %d migrations are pending", ErrNotMigrated, pending)
+	}
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS issues (
-		projectId	string not null,
-		id		string not null,
-		title		string not null,
-		jiraUrl		string,
-		jiraIssueType	string,
-		estimate	int,
-		status		string,
-		assignees	string,
-		repository	string,
-		primary key (projectId, id)
-	)`)
+	return newSqlModels(db), nil
+}
+
+// InitializeInMemory creates an empty and migrated database that only lives
+// in memory, it stands for a local storage that doesn't exist yet.
+func InitializeInMemory(backend Backend) (*Models, error) {
+	db, err := openBackend(backend, ":memory:", false)
 	if err != nil {
 		return nil, err
 	}
 
-	models.db = db
-	models.Projects = Projects{models: models}
-	models.Issues = Issues{models: models}
-	return models, nil
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return newSqlModels(db), nil
+}
+
+// newSqlModels returns the repositories kept within a sql database.
+func newSqlModels(db *sql.DB) *Models {
+	models := &Models{close: db.Close}
+	models.Projects = &Projects{db: db, models: models}
+	models.Issues = &Issues{db: db}
+	models.Tombstones = &Tombstones{db: db}
+	models.Operations = &Operations{db: db}
+	models.Audits = &Audits{db: db}
+	models.History = &IssueHistory{db: db}
+	return models
+}
+
+func open(backend Backend, path string) (*sql.DB, error) {
+	if path == "" {
+		path = DEFAULT_DB_PATH
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	return openBackend(backend, path, false)
 }
@@ -0,0 +1,240 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OperationKind string
+
+const (
+	OPERATION_KIND_CREATE_ISSUE     OperationKind = "create_issue"
+	OPERATION_KIND_TRANSITION_ISSUE OperationKind = "transition_issue"
+)
+
+type OperationStatus string
+
+const (
+	OPERATION_STATUS_PENDING OperationStatus = "pending"
+	OPERATION_STATUS_DONE    OperationStatus = "done"
+)
+
+// Operation is a journal entry of a multi-step operation. Every step is
+// recorded once it succeeds so the operation can be resumed from the last
+// completed step without repeating the ones that already succeeded.
+type Operation struct {
+	ID              string
+	GitHubProjectID string
+	GitHubID        string
+	Kind            OperationKind
+	Status          OperationStatus
+	CompletedSteps  []string
+	JiraKey         *string
+	Payload         string // json encoded issue the operation was started with
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// HasCompleted reports whether the given step was already completed.
+func (op Operation) HasCompleted(step string) bool {
+	return slices.Contains(op.CompletedSteps, step)
+}
+
+// Issue decodes the issue the operation was started with.
+func (op Operation) Issue() (*Issue, error) {
+	issue := new(Issue)
+	if err := json.Unmarshal([]byte(op.Payload), issue); err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+type Operations struct {
+	db *sql.DB
+}
+
+// Begin records a new pending operation before any of its steps is run. If
+// there's already a pending operation of the same kind for the item, it is
+// returned instead (with its payload updated) so it can be resumed.
+func (service *Operations) Begin(projectId, id string, kind OperationKind, issue Issue) (*Operation, error) {
+	b, err := json.Marshal(issue)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := service.GetPending(projectId, id, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if op != nil {
+		op.Payload = string(b)
+		op.UpdatedAt = now
+		stmt := `UPDATE operations SET payload = ?, updatedAt = ? WHERE id = ?`
+		if _, err := service.db.Exec(stmt, op.Payload, op.UpdatedAt, op.ID); err != nil {
+			return nil, err
+		}
+		return op, nil
+	}
+
+	op = new(Operation)
+	op.ID = uuid.NewString()
+	op.GitHubProjectID = projectId
+	op.GitHubID = id
+	op.Kind = kind
+	op.Status = OPERATION_STATUS_PENDING
+	op.CompletedSteps = []string{}
+	op.Payload = string(b)
+	op.CreatedAt = now
+	op.UpdatedAt = now
+
+	stmt := `INSERT INTO operations(
+			id,
+			projectId,
+			itemId,
+			kind,
+			status,
+			completedSteps,
+			jiraKey,
+			payload,
+			createdAt,
+			updatedAt
+		) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = service.db.Exec(
+		stmt,
+		op.ID,
+		op.GitHubProjectID,
+		op.GitHubID,
+		op.Kind,
+		op.Status,
+		"",
+		op.JiraKey,
+		op.Payload,
+		op.CreatedAt,
+		op.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// CompleteStep records a step of the operation as completed.
+func (service *Operations) CompleteStep(op *Operation, step string) error {
+	if op.HasCompleted(step) {
+		return nil
+	}
+
+	completedSteps := append(slices.Clone(op.CompletedSteps), step)
+	updatedAt := time.Now().UTC()
+	stmt := `UPDATE operations SET completedSteps = ?, jiraKey = ?, updatedAt = ? WHERE id = ?`
+	_, err := service.db.Exec(stmt, strings.Join(completedSteps, ";"), op.JiraKey, updatedAt, op.ID)
+	if err != nil {
+		return err
+	}
+
+	op.CompletedSteps = completedSteps
+	op.UpdatedAt = updatedAt
+	return nil
+}
+
+// Finish marks the operation as done.
+func (service *Operations) Finish(op *Operation) error {
+	updatedAt := time.Now().UTC()
+	stmt := `UPDATE operations SET status = ?, updatedAt = ? WHERE id = ?`
+	_, err := service.db.Exec(stmt, OPERATION_STATUS_DONE, updatedAt, op.ID)
+	if err != nil {
+		return err
+	}
+
+	op.Status = OPERATION_STATUS_DONE
+	op.UpdatedAt = updatedAt
+	return nil
+}
+
+// GetPending retrieves the pending operation of the given kind for an item,
+// if no operation is found *Operation will be nil.
+func (service *Operations) GetPending(projectId, id string, kind OperationKind) (*Operation, error) {
+	if projectId == "" {
+		return nil, errors.New(`please provide a value for "githubProjectId"`)
+	}
+	if id == "" {
+		return nil, errors.New(`please provide a value for "githubId"`)
+	}
+
+	ops, err := service.query(`WHERE projectId = ? AND itemId = ? AND kind = ? AND status = ?`, projectId, id, kind, OPERATION_STATUS_PENDING)
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) == 0 {
+		return nil, nil
+	}
+
+	return ops[0], nil
+}
+
+// GetAllPending retrieves every pending operation of a project.
+func (service *Operations) GetAllPending(projectId string) ([]*Operation, error) {
+	if projectId == "" {
+		return nil, errors.New(`please provide a value for "githubProjectId"`)
+	}
+
+	return service.query(`WHERE projectId = ? AND status = ? ORDER BY createdAt`, projectId, OPERATION_STATUS_PENDING)
+}
+
+func (service *Operations) query(where string, args ...any) ([]*Operation, error) {
+	stmt := `SELECT
+		id,
+		projectId,
+		itemId,
+		kind,
+		status,
+		completedSteps,
+		jiraKey,
+		payload,
+		createdAt,
+		updatedAt
+	FROM operations
+	` + where
+	rows, err := service.db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ops := []*Operation{}
+	for rows.Next() {
+		op := new(Operation)
+		var completedSteps string
+		err = rows.Scan(
+			&op.ID,
+			&op.GitHubProjectID,
+			&op.GitHubID,
+			&op.Kind,
+			&op.Status,
+			&completedSteps,
+			&op.JiraKey,
+			&op.Payload,
+			&op.CreatedAt,
+			&op.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		op.CompletedSteps = []string{}
+		if completedSteps != "" {
+			op.CompletedSteps = strings.Split(completedSteps, ";")
+		}
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
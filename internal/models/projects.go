@@ -1,8 +1,8 @@
 package models
 
 import (
+	"database/sql"
 	"errors"
-	"fmt"
 )
 
 const (
@@ -41,6 +41,10 @@ func (p Project) UpdateIssueUrl(id, jiraUrl string) error {
 	return p.models.Issues.UpdateUrl(p.ID, id, jiraUrl)
 }
 
+func (p Project) ClearIssueUrl(id string) error {
+	return p.models.Issues.ClearUrl(p.ID, id)
+}
+
 func (p Project) GetIssue(id string) (*Issue, error) {
 	return p.models.Issues.Get(p.ID, id)
 }
@@ -69,11 +73,57 @@ func (p Project) FindIssuesThatDoesntExist(ids []string) ([]string, error) {
 	return p.models.Issues.FindThoseThatDoesntExist(p.ID, ids)
 }
 
+func (p Project) GetIssuesNotIn(ids []string) ([]*Issue, error) {
+	return p.models.Issues.GetThoseNotIn(p.ID, ids)
+}
+
+func (p Project) TombstoneIssue(is Issue, action string) (*Tombstone, error) {
+	is.GitHubProjectID = p.ID
+	return p.models.Tombstones.Add(is, action)
+}
+
+func (p Project) GetTombstone(id string) (*Tombstone, error) {
+	return p.models.Tombstones.Get(p.ID, id)
+}
+
+func (p Project) DeleteTombstone(id string) error {
+	return p.models.Tombstones.Delete(p.ID, id)
+}
+
+func (p Project) BeginOperation(id string, kind OperationKind, is Issue) (*Operation, error) {
+	return p.models.Operations.Begin(p.ID, id, kind, is)
+}
+
+func (p Project) CompleteOperationStep(op *Operation, step string) error {
+	return p.models.Operations.CompleteStep(op, step)
+}
+
+func (p Project) FinishOperation(op *Operation) error {
+	return p.models.Operations.Finish(op)
+}
+
+func (p Project) GetPendingOperations() ([]*Operation, error) {
+	return p.models.Operations.GetAllPending(p.ID)
+}
+
+func (p Project) RecordIssuesStatus(issues []RemoteIssue) (int, error) {
+	return p.models.History.RecordMany(p.ID, issues)
+}
+
+func (p Project) GetIssuesHistory() ([]*IssueStatusChange, error) {
+	return p.models.History.GetAll(p.ID)
+}
+
+func (p Project) RecordAudit(id, jiraKey string, action AuditAction, summary string, mutationErr error) (*AuditEntry, error) {
+	return p.models.Audits.Add(p.ID, id, jiraKey, action, summary, mutationErr)
+}
+
 type Projects struct {
+	db     *sql.DB
 	models *Models
 }
 
-func (p Projects) Upsert(id, jiraUrlId, jiraIssueTypeId, titleId, estimateId, statusId, assigneesId, repositoryId string) (*Project, error) {
+func (p *Projects) Upsert(id, jiraUrlId, jiraIssueTypeId, titleId, estimateId, statusId, assigneesId, repositoryId string) (*Project, error) {
 	project := new(Project)
 	project.models = p.models
 	project.ID = id
@@ -94,7 +144,7 @@ func (p Projects) Upsert(id, jiraUrlId, jiraIssueTypeId, titleId, estimateId, st
 			FID_assignees,
 			FID_repository
 		) values(?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := p.models.db.Exec(
+	_, err := p.db.Exec(
 		stmt,
 		project.ID,
 		project.Fields.JiraURL,
@@ -118,7 +168,7 @@ func (p *Projects) Get(githubId string) (*Project, error) {
 		return nil, errors.New(`please provide a value for "githubId"`)
 	}
 
-	stmt := fmt.Sprintf(`SELECT
+	stmt := `SELECT
 		id,
 		FID_jiraUrl,
 		FID_jiraIssueType,
@@ -128,28 +178,22 @@ func (p *Projects) Get(githubId string) (*Project, error) {
 		FID_assignees,
 		FID_repository
 	FROM projects
-	WHERE id = "%s"
-	`, githubId)
-	rows, err := p.models.db.Query(stmt)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	project := new(Project)
-	if !rows.Next() {
-		return nil, nil
-	}
-	err = rows.Scan(
-		&project.ID,
-		&project.Fields.JiraURL,
-		&project.Fields.JiraIssueType,
-		&project.Fields.Title,
-		&project.Fields.Estimate,
-		&project.Fields.Status,
-		&project.Fields.Assignees,
-		&project.Fields.Repository,
-	)
-	if err != nil {
+	WHERE id = ?`
+	project, ok, err := queryOne(p.db, func(row rowScanner) (*Project, error) {
+		project := new(Project)
+		err := row.Scan(
+			&project.ID,
+			&project.Fields.JiraURL,
+			&project.Fields.JiraIssueType,
+			&project.Fields.Title,
+			&project.Fields.Estimate,
+			&project.Fields.Status,
+			&project.Fields.Assignees,
+			&project.Fields.Repository,
+		)
+		return project, err
+	}, stmt, githubId)
+	if err != nil || !ok {
 		return nil, err
 	}
 	project.models = p.models
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// QUERY_IN_CHUNK_SIZE is the maximum number of values bound to a single
+// "IN (...)" list, it keeps queries under the sqlite variables limit.
+const QUERY_IN_CHUNK_SIZE = 500
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// queryAll runs a prepared statement with the given bound args and scans
+// every resulting row with scan.
+func queryAll[T any](db *sql.DB, scan func(rowScanner) (T, error), stmt string, args ...any) ([]T, error) {
+	prepared, err := db.Prepare(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer prepared.Close()
+
+	rows, err := prepared.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+// queryOne runs a prepared statement with the given bound args and scans
+// its first row with scan, ok is false if there's no row.
+func queryOne[T any](db *sql.DB, scan func(rowScanner) (T, error), stmt string, args ...any) (item T, ok bool, err error) {
+	prepared, err := db.Prepare(stmt)
+	if err != nil {
+		return item, false, err
+	}
+	defer prepared.Close()
+
+	item, err = scan(prepared.QueryRow(args...))
+	if err == sql.ErrNoRows {
+		return item, false, nil
+	}
+	if err != nil {
+		return item, false, err
+	}
+
+	return item, true, nil
+}
+
+// placeholders returns n comma separated bound parameters placeholders.
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
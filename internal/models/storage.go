@@ -0,0 +1,137 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+)
+
+// Backend is a database/sql driver the local storage can be kept with.
+type Backend string
+
+const (
+	// BACKEND_SQLITE is a pure Go sqlite driver, binaries built with it
+	// don't require cgo.
+	BACKEND_SQLITE Backend = "sqlite"
+	// BACKEND_SQLITE_CGO is the cgo sqlite driver, it is only available in
+	// binaries built with CGO_ENABLED=1.
+	BACKEND_SQLITE_CGO Backend = "sqlite-cgo"
+)
+
+// DEFAULT_BACKEND is the backend used when none is configured.
+const DEFAULT_BACKEND = BACKEND_SQLITE
+
+// BUSY_TIMEOUT_MS is how long a connection waits for the database lock held
+// by another one (i.e. another command) before failing with SQLITE_BUSY.
+const BUSY_TIMEOUT_MS = 5000
+
+// backendDriver holds the database/sql driver name of a backend and how to
+// build its data source name from the database path.
+type backendDriver struct {
+	name string
+	dsn  func(path string, readOnly bool) string
+}
+
+// backends holds the backends compiled into the binary.
+var backends = map[Backend]backendDriver{
+	BACKEND_SQLITE: {
+		name: "sqlite",
+		dsn: func(path string, readOnly bool) string {
+			// times are written in the same format the cgo driver uses, so
+			// a database can be moved from a backend to the other
+			params := url.Values{"_time_format": {"sqlite"}}
+			params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", BUSY_TIMEOUT_MS))
+			if readOnly {
+				params.Set("mode", "ro")
+			} else {
+				params.Add("_pragma", "journal_mode(WAL)")
+			}
+			return "file:" + path + "?" + params.Encode()
+		},
+	},
+}
+
+// openBackend opens the database at the given path with the given backend.
+// Writable databases use a single connection, so the sync loops and the api
+// writing at once wait for each other instead of failing with SQLITE_BUSY.
+func openBackend(backend Backend, path string, readOnly bool) (*sql.DB, error) {
+	if backend == "" {
+		backend = DEFAULT_BACKEND
+	}
+
+	driver, ok := backends[backend]
+	if !ok {
+		return nil, fmt.Errorf(`storage backend "%s" is not available in this build`, backend)
+	}
+
+	db, err := sql.Open(driver.name, driver.dsn(path, readOnly))
+	if err != nil {
+		return nil, err
+	}
+	if !readOnly {
+		db.SetMaxOpenConns(1)
+	}
+	return db, nil
+}
+
+// ProjectsRepository stores the synced GitHub projects along with their
+// fields ids.
+type ProjectsRepository interface {
+	Upsert(id, jiraUrlId, jiraIssueTypeId, titleId, estimateId, statusId, assigneesId, repositoryId string) (*Project, error)
+	Get(githubId string) (*Project, error)
+}
+
+// IssuesRepository stores the synced GitHub projects items.
+type IssuesRepository interface {
+	Upsert(projectId, id, title string, status *IssueStatus, jiraUrl, jiraIssueType, repo *string, estimate *int, assignees *[]string) (*Issue, error)
+	UpsertMany(projectId string, issues []RemoteIssue) ([]*Issue, error)
+	UpdateUrl(projectId, id, jiraUrl string) error
+	ClearUrl(projectId, id string) error
+	Get(githubProjectId, githubId string) (*Issue, error)
+	GetAll(githubProjectId string) ([]*Issue, error)
+	GetThoseWithDiff(projectId string, issues []RemoteIssue) ([]Diff, error)
+	GetWithoutUrl(githubProjectId string) ([]*Issue, error)
+	GetWithUrl(githubProjectId string) ([]*Issue, error)
+	FindThoseThatExist(githubProjectId string, ids []string) ([]string, error)
+	FindThoseThatDoesntExist(githubProjectId string, ids []string) ([]string, error)
+	GetThoseNotIn(githubProjectId string, ids []string) ([]*Issue, error)
+}
+
+// TombstonesRepository stores the items removed from their GitHub project.
+type TombstonesRepository interface {
+	Add(issue Issue, action string) (*Tombstone, error)
+	Get(githubProjectId, githubId string) (*Tombstone, error)
+	Delete(githubProjectId, githubId string) error
+}
+
+// OperationsRepository stores the journal of the multi step Jira operations.
+type OperationsRepository interface {
+	Begin(projectId, id string, kind OperationKind, issue Issue) (*Operation, error)
+	CompleteStep(op *Operation, step string) error
+	Finish(op *Operation) error
+	GetPending(projectId, id string, kind OperationKind) (*Operation, error)
+	GetAllPending(projectId string) ([]*Operation, error)
+}
+
+// AuditsRepository stores the actions taken against Jira and GitHub.
+type AuditsRepository interface {
+	Add(projectId, id, jiraKey string, action AuditAction, summary string, mutationErr error) (*AuditEntry, error)
+	List(filter AuditFilter) ([]*AuditEntry, error)
+}
+
+// IssueHistoryRepository stores the status changes of the GitHub projects
+// items.
+type IssueHistoryRepository interface {
+	RecordMany(projectId string, issues []RemoteIssue) (int, error)
+	GetAll(projectId string) ([]*IssueStatusChange, error)
+	GetByItem(projectId, itemId string) ([]*IssueStatusChange, error)
+}
+
+var (
+	_ ProjectsRepository     = (*Projects)(nil)
+	_ IssuesRepository       = (*Issues)(nil)
+	_ TombstonesRepository   = (*Tombstones)(nil)
+	_ OperationsRepository   = (*Operations)(nil)
+	_ AuditsRepository       = (*Audits)(nil)
+	_ IssueHistoryRepository = (*IssueHistory)(nil)
+)
@@ -0,0 +1,25 @@
+//go:build cgo
+
+package models
+
+import (
+	"net/url"
+	"strconv"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	backends[BACKEND_SQLITE_CGO] = backendDriver{
+		name: "sqlite3",
+		dsn: func(path string, readOnly bool) string {
+			params := url.Values{"_busy_timeout": {strconv.Itoa(BUSY_TIMEOUT_MS)}}
+			if readOnly {
+				params.Set("mode", "ro")
+			} else {
+				params.Set("_journal_mode", "WAL")
+			}
+			return "file:" + path + "?" + params.Encode()
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentWrites(t *testing.T) {
+	const writers, writes = 8, 25
+
+	for backend := range backends {
+		t.Run(string(backend), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "storage.db")
+
+			// two handles stand for the sync command and another command
+			// (i.e. "link") writing to the same database at once
+			handles := make([]*Models, 2)
+			for i := range handles {
+				m, err := Initialize(backend, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer m.Close()
+				handles[i] = m
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, writers*writes*3)
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					m := handles[w%len(handles)]
+					status := STATUS_TODO
+					for i := 0; i < writes; i++ {
+						id := fmt.Sprintf("item-%d-%d", w, i)
+						if _, err := m.Audits.Add("P", id, "", AUDIT_ACTION_JIRA_CREATE, "create", nil); err != nil {
+							errs <- err
+						}
+						is, err := m.Issues.Upsert("P", id, id, &status, nil, nil, nil, nil, nil)
+						if err != nil {
+							errs <- err
+							continue
+						}
+						// tombstones are added within a transaction
+						if _, err := m.Tombstones.Add(*is, "comment"); err != nil {
+							errs <- err
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				t.Fatalf("concurrent write failed: %v", err)
+			}
+
+			entries, err := handles[0].Audits.List(AuditFilter{Limit: writers * writes * 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != writers*writes {
+				t.Errorf("got %d audit entries, want %d", len(entries), writers*writes)
+			}
+			for w := 0; w < writers; w++ {
+				for i := 0; i < writes; i++ {
+					id := fmt.Sprintf("item-%d-%d", w, i)
+					if tombstone, err := handles[1].Tombstones.Get("P", id); err != nil || tombstone == nil {
+						t.Fatalf("got tombstone %v (err %v) for %s, want one", tombstone, err, id)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestInitializeInMemory(t *testing.T) {
+	for backend := range backends {
+		t.Run(string(backend), func(t *testing.T) {
+			m, err := InitializeInMemory(backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+
+			if p, err := m.Projects.Get("P"); err != nil || p != nil {
+				t.Fatalf("got project %v (err %v), want none", p, err)
+			}
+			// every query must see the same in-memory database
+			if _, err := m.Projects.Upsert("P", "a", "b", "c", "d", "e", "f", "g"); err != nil {
+				t.Fatal(err)
+			}
+			if p, err := m.Projects.Get("P"); err != nil || p == nil {
+				t.Fatalf("got project %v (err %v), want the upserted one", p, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Tombstone is the record left behind by an issue whose GitHub project
+// item was removed from the project or archived.
+type Tombstone struct {
+	GitHubProjectID string
+	GitHubID        string
+	Title           string
+	JiraURL         *string
+	Action          string // action that was taken against the jira issue
+	RemovedAt       time.Time
+}
+
+type Tombstones struct {
+	db *sql.DB
+}
+
+// Add records a tombstone for the given issue and removes it from the issues
+// table within the same transaction.
+func (service *Tombstones) Add(issue Issue, action string) (*Tombstone, error) {
+	tombstone := new(Tombstone)
+	tombstone.GitHubProjectID = issue.GitHubProjectID
+	tombstone.GitHubID = issue.GitHubID
+	tombstone.Title = issue.Title
+	tombstone.JiraURL = issue.JiraURL
+	tombstone.Action = action
+	tombstone.RemovedAt = time.Now().UTC()
+
+	tx, err := service.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	stmt := `INSERT OR REPLACE INTO tombstones(
+			projectId,
+			id,
+			title,
+			jiraUrl,
+			action,
+			removedAt
+		) values(?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(
+		stmt,
+		tombstone.GitHubProjectID,
+		tombstone.GitHubID,
+		tombstone.Title,
+		tombstone.JiraURL,
+		tombstone.Action,
+		tombstone.RemovedAt,
+	)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	stmt = `DELETE FROM issues WHERE projectId = ? AND id = ?`
+	_, err = tx.Exec(stmt, tombstone.GitHubProjectID, tombstone.GitHubID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return tombstone, nil
+}
+
+// Get retrieves a tombstone, if no tombstone found *Tombstone will be nil.
+func (service *Tombstones) Get(githubProjectId, githubId string) (*Tombstone, error) {
+	if githubProjectId == "" {
+		return nil, errors.New(`please provide a value for "githubProjectId"`)
+	}
+	if githubId == "" {
+		return nil, errors.New(`please provide a value for "githubId"`)
+	}
+
+	stmt := `SELECT
+		projectId,
+		id,
+		title,
+		jiraUrl,
+		action,
+		removedAt
+	FROM tombstones
+	WHERE projectId = ?
+	AND id = ?
+	`
+	rows, err := service.db.Query(stmt, githubProjectId, githubId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, nil
+	}
+	tombstone := new(Tombstone)
+	err = rows.Scan(
+		&tombstone.GitHubProjectID,
+		&tombstone.GitHubID,
+		&tombstone.Title,
+		&tombstone.JiraURL,
+		&tombstone.Action,
+		&tombstone.RemovedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return tombstone, nil
+}
+
+// Delete removes a tombstone, it is meant to be used when an item that was
+// previously removed shows up again in the GitHub project.
+func (service *Tombstones) Delete(githubProjectId, githubId string) error {
+	stmt := `DELETE FROM tombstones WHERE projectId = ? AND id = ?`
+	_, err := service.db.Exec(stmt, githubProjectId, githubId)
+	return err
+}
@@ -0,0 +1,215 @@
+package report
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+)
+
+// ways items can be grouped by
+const (
+	GROUP_BY_ITEM       = "item"
+	GROUP_BY_REPOSITORY = "repository"
+	GROUP_BY_ISSUE_TYPE = "issue-type"
+	GROUP_BY_ASSIGNEE   = "assignee"
+)
+
+// NONE is the group of the items that lack of the grouping attribute.
+const NONE = "(none)"
+
+// ItemCycleTime holds the cycle time metrics of a GitHub project item.
+// Lead time goes from the item being first seen to it being done, cycle time
+// from it first being in progress to it being done.
+type ItemCycleTime struct {
+	GitHubProjectID string
+	GitHubID        string
+	Title           string
+	Status          string // current status
+	JiraIssueType   *string
+	Repository      *string
+	Assignees       []string
+	FirstSeenAt     time.Time
+	StartedAt       *time.Time // nil if the item was never in progress
+	DoneAt          *time.Time // nil if the item is not done
+	LeadTime        *time.Duration
+	CycleTime       *time.Duration
+	TimeInStatus    map[string]time.Duration
+}
+
+// GroupCycleTime holds the aggregated cycle time metrics of a group of
+// items, lead and cycle times only take done items into account.
+type GroupCycleTime struct {
+	Group              string
+	Items              int
+	Done               int
+	AvgLeadTime        *time.Duration
+	MedianLeadTime     *time.Duration
+	AvgCycleTime       *time.Duration
+	MedianCycleTime    *time.Duration
+	AvgTimeInStatus    map[string]time.Duration
+	timeInStatusTotals map[string]time.Duration
+}
+
+// GetItemsCycleTime computes the cycle time metrics of every item from its
+// status changes, which must be sorted by item and time. The time spent in
+// the current status counts until now, unless the item is done.
+func GetItemsCycleTime(changes []*models.IssueStatusChange, now time.Time) []ItemCycleTime {
+	items := []ItemCycleTime{}
+
+	for start := 0; start < len(changes); {
+		end := start
+		for end < len(changes) &&
+			changes[end].GitHubProjectID == changes[start].GitHubProjectID &&
+			changes[end].GitHubID == changes[start].GitHubID {
+			end++
+		}
+		items = append(items, getItemCycleTime(changes[start:end], now))
+		start = end
+	}
+
+	return items
+}
+
+func getItemCycleTime(changes []*models.IssueStatusChange, now time.Time) ItemCycleTime {
+	last := changes[len(changes)-1]
+	item := ItemCycleTime{
+		GitHubProjectID: last.GitHubProjectID,
+		GitHubID:        last.GitHubID,
+		Title:           last.Title,
+		Status:          last.Status,
+		JiraIssueType:   last.JiraIssueType,
+		Repository:      last.Repository,
+		Assignees:       last.Assignees,
+		FirstSeenAt:     changes[0].ChangedAt,
+		TimeInStatus:    map[string]time.Duration{},
+	}
+
+	for i, change := range changes {
+		if change.Status == string(models.STATUS_WIP) && item.StartedAt == nil {
+			startedAt := change.ChangedAt
+			item.StartedAt = &startedAt
+		}
+
+		if i+1 < len(changes) {
+			item.TimeInStatus[change.Status] += changes[i+1].ChangedAt.Sub(change.ChangedAt)
+		} else if change.Status != string(models.STATUS_DONE) {
+			item.TimeInStatus[change.Status] += now.Sub(change.ChangedAt)
+		}
+	}
+
+	if last.Status == string(models.STATUS_DONE) {
+		doneAt := last.ChangedAt
+		item.DoneAt = &doneAt
+		leadTime := doneAt.Sub(item.FirstSeenAt)
+		item.LeadTime = &leadTime
+		if item.StartedAt != nil {
+			cycleTime := doneAt.Sub(*item.StartedAt)
+			item.CycleTime = &cycleTime
+		}
+	}
+
+	return item
+}
+
+// GroupItems aggregates the items cycle time metrics by repository, issue
+// type or assignee. Items with many assignees count for each one of them.
+func GroupItems(items []ItemCycleTime, groupBy string) []GroupCycleTime {
+	groups := map[string]*GroupCycleTime{}
+	leadTimes := map[string][]time.Duration{}
+	cycleTimes := map[string][]time.Duration{}
+
+	for _, item := range items {
+		for _, key := range getGroupKeys(item, groupBy) {
+			group, ok := groups[key]
+			if !ok {
+				group = &GroupCycleTime{Group: key, timeInStatusTotals: map[string]time.Duration{}}
+				groups[key] = group
+			}
+
+			group.Items++
+			for status, d := range item.TimeInStatus {
+				group.timeInStatusTotals[status] += d
+			}
+			if item.LeadTime != nil {
+				group.Done++
+				leadTimes[key] = append(leadTimes[key], *item.LeadTime)
+			}
+			if item.CycleTime != nil {
+				cycleTimes[key] = append(cycleTimes[key], *item.CycleTime)
+			}
+		}
+	}
+
+	result := []GroupCycleTime{}
+	for key, group := range groups {
+		group.AvgLeadTime, group.MedianLeadTime = getAvgAndMedian(leadTimes[key])
+		group.AvgCycleTime, group.MedianCycleTime = getAvgAndMedian(cycleTimes[key])
+		group.AvgTimeInStatus = map[string]time.Duration{}
+		for status, total := range group.timeInStatusTotals {
+			group.AvgTimeInStatus[status] = total / time.Duration(group.Items)
+		}
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Group < result[j].Group })
+
+	return result
+}
+
+func getGroupKeys(item ItemCycleTime, groupBy string) []string {
+	var key *string
+	switch groupBy {
+	case GROUP_BY_REPOSITORY:
+		key = item.Repository
+	case GROUP_BY_ISSUE_TYPE:
+		key = item.JiraIssueType
+	case GROUP_BY_ASSIGNEE:
+		if len(item.Assignees) > 0 {
+			return item.Assignees
+		}
+	default:
+		key = &item.GitHubID
+	}
+
+	if key == nil || *key == "" {
+		return []string{NONE}
+	}
+	return []string{*key}
+}
+
+func getAvgAndMedian(durations []time.Duration) (avg, median *time.Duration) {
+	if len(durations) == 0 {
+		return nil, nil
+	}
+
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	a := total / time.Duration(len(sorted))
+
+	m := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		m = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	return &a, &m
+}
+
+// GetStatuses returns every status items spent time in, sorted by name.
+func GetStatuses(items []ItemCycleTime) []string {
+	statuses := []string{}
+	for _, item := range items {
+		for status := range item.TimeInStatus {
+			if !slices.Contains(statuses, status) {
+				statuses = append(statuses, status)
+			}
+		}
+	}
+	slices.Sort(statuses)
+	return statuses
+}
@@ -0,0 +1,122 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/iolave/jira-tickets-from-gh/internal/models"
+)
+
+func TestGetItemsCycleTime(t *testing.T) {
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	now := start.Add(100 * time.Hour)
+	at := func(hours int) time.Time { return start.Add(time.Duration(hours) * time.Hour) }
+	change := func(item string, status models.IssueStatus, hours int) *models.IssueStatusChange {
+		return &models.IssueStatusChange{GitHubProjectID: "P", GitHubID: item, Title: item, Status: string(status), ChangedAt: at(hours)}
+	}
+	ptr := func(hours int) *time.Time { t := at(hours); return &t }
+	duration := func(hours int) *time.Duration { d := time.Duration(hours) * time.Hour; return &d }
+
+	tests := []struct {
+		name    string
+		changes []*models.IssueStatusChange
+		want    []ItemCycleTime
+	}{
+		{name: "no changes", changes: nil, want: []ItemCycleTime{}},
+		{
+			name: "done item",
+			changes: []*models.IssueStatusChange{
+				change("a", models.STATUS_TODO, 0),
+				change("a", models.STATUS_WIP, 10),
+				change("a", models.STATUS_DONE, 30),
+			},
+			want: []ItemCycleTime{{
+				GitHubProjectID: "P", GitHubID: "a", Title: "a", Status: string(models.STATUS_DONE),
+				FirstSeenAt: at(0), StartedAt: ptr(10), DoneAt: ptr(30),
+				LeadTime: duration(30), CycleTime: duration(20),
+				TimeInStatus: map[string]time.Duration{
+					string(models.STATUS_TODO): 10 * time.Hour,
+					string(models.STATUS_WIP):  20 * time.Hour,
+				},
+			}},
+		},
+		{
+			name: "item in progress counts until now",
+			changes: []*models.IssueStatusChange{
+				change("a", models.STATUS_TODO, 0),
+				change("a", models.STATUS_WIP, 40),
+			},
+			want: []ItemCycleTime{{
+				GitHubProjectID: "P", GitHubID: "a", Title: "a", Status: string(models.STATUS_WIP),
+				FirstSeenAt: at(0), StartedAt: ptr(40),
+				TimeInStatus: map[string]time.Duration{
+					string(models.STATUS_TODO): 40 * time.Hour,
+					string(models.STATUS_WIP):  60 * time.Hour,
+				},
+			}},
+		},
+		{
+			name: "item done without being in progress has no cycle time",
+			changes: []*models.IssueStatusChange{
+				change("a", models.STATUS_TODO, 0),
+				change("a", models.STATUS_DONE, 5),
+			},
+			want: []ItemCycleTime{{
+				GitHubProjectID: "P", GitHubID: "a", Title: "a", Status: string(models.STATUS_DONE),
+				FirstSeenAt: at(0), DoneAt: ptr(5), LeadTime: duration(5),
+				TimeInStatus: map[string]time.Duration{string(models.STATUS_TODO): 5 * time.Hour},
+			}},
+		},
+		{
+			name: "reopened item starts at its first progress and sums the time per status",
+			changes: []*models.IssueStatusChange{
+				change("a", models.STATUS_WIP, 0),
+				change("a", models.STATUS_DONE, 10),
+				change("a", models.STATUS_WIP, 20),
+				change("a", models.STATUS_DONE, 25),
+			},
+			want: []ItemCycleTime{{
+				GitHubProjectID: "P", GitHubID: "a", Title: "a", Status: string(models.STATUS_DONE),
+				FirstSeenAt: at(0), StartedAt: ptr(0), DoneAt: ptr(25),
+				LeadTime: duration(25), CycleTime: duration(25),
+				TimeInStatus: map[string]time.Duration{
+					string(models.STATUS_WIP):  15 * time.Hour,
+					string(models.STATUS_DONE): 10 * time.Hour,
+				},
+			}},
+		},
+		{
+			name: "changes are split by item",
+			changes: []*models.IssueStatusChange{
+				change("a", models.STATUS_TODO, 0),
+				change("b", models.STATUS_TODO, 50),
+				change("b", "Blocked", 60),
+			},
+			want: []ItemCycleTime{
+				{
+					GitHubProjectID: "P", GitHubID: "a", Title: "a", Status: string(models.STATUS_TODO),
+					FirstSeenAt:  at(0),
+					TimeInStatus: map[string]time.Duration{string(models.STATUS_TODO): 100 * time.Hour},
+				},
+				{
+					GitHubProjectID: "P", GitHubID: "b", Title: "b", Status: "Blocked",
+					FirstSeenAt: at(50),
+					TimeInStatus: map[string]time.Duration{
+						string(models.STATUS_TODO): 10 * time.Hour,
+						"Blocked":                  40 * time.Hour,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetItemsCycleTime(tt.changes, now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxWindowLookups bounds the search of a run time that falls within the
+// active windows.
+const maxWindowLookups = 1000
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a daily time range in which runs are allowed. If To is before
+// From the window spans midnight.
+type Window struct {
+	Days []time.Weekday // days the window starts at, every day if empty
+	From time.Duration  // offset from midnight
+	To   time.Duration  // offset from midnight
+}
+
+// ParseWindow parses a window from a list of week days (sun, mon, ...) and
+// a "15:04" formatted start and end time.
+func ParseWindow(days []string, from, to string) (Window, error) {
+	window := Window{}
+
+	for _, day := range days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return window, fmt.Errorf(`invalid week day "%s"`, day)
+		}
+		window.Days = append(window.Days, weekday)
+	}
+
+	var err error
+	if window.From, err = parseClock(from); err != nil {
+		return window, err
+	}
+	if window.To, err = parseClock(to); err != nil {
+		return window, err
+	}
+	if window.From == window.To {
+		return window, errors.New("window start and end times should be different")
+	}
+
+	return window, nil
+}
+
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf(`invalid time "%s", expected format is "15:04"`, clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w Window) includesDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether t (already in the schedule location) falls
+// within the window.
+func (w Window) contains(t time.Time) bool {
+	offset := sinceMidnight(t)
+
+	if w.From < w.To {
+		return w.includesDay(t.Weekday()) && offset >= w.From && offset < w.To
+	}
+
+	// windows spanning midnight belong to the day they start at
+	if offset >= w.From {
+		return w.includesDay(t.Weekday())
+	}
+	return offset < w.To && w.includesDay(t.AddDate(0, 0, -1).Weekday())
+}
+
+// nextStart returns the first window start after t.
+func (w Window) nextStart(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for i := 0; i <= 7; i++ {
+		day := midnight.AddDate(0, 0, i)
+		start := day.Add(w.From)
+		if start.After(t) && w.includesDay(day.Weekday()) {
+			return start
+		}
+	}
+	return t
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())
+}
+
+// Schedule computes the run times of a sync loop.
+type Schedule struct {
+	interval time.Duration
+	cron     cron.Schedule
+	jitter   time.Duration
+	location *time.Location
+	windows  []Window
+}
+
+// New creates a schedule from an expression that is either a go duration
+// (i.e. "5m") or a standard 5 fields cron expression (i.e. "*/5 * * * *").
+// Run times are delayed by a random duration up to jitter and, when
+// windows are given, only fall within them.
+func New(expression string, jitter time.Duration, location *time.Location, windows []Window) (*Schedule, error) {
+	if location == nil {
+		location = time.Local
+	}
+	if jitter < 0 {
+		return nil, errors.New("jitter should not be negative")
+	}
+
+	s := &Schedule{jitter: jitter, location: location, windows: windows}
+
+	if interval, err := time.ParseDuration(expression); err == nil {
+		if interval <= 0 {
+			return nil, errors.New("interval should be greater than 0")
+		}
+		s.interval = interval
+		return s, nil
+	}
+
+	sched, err := cron.ParseStandard(expression)
+	if err != nil {
+		return nil, fmt.Errorf(`"%s" is neither a duration nor a valid cron expression: %w`, expression, err)
+	}
+	s.cron = sched
+
+	return s, nil
+}
+
+// Every creates a schedule that runs every interval.
+func Every(interval time.Duration) *Schedule {
+	return &Schedule{interval: interval, location: time.Local}
+}
+
+// Next returns the next run time after now.
+func (s *Schedule) Next(now time.Time) time.Time {
+	now = now.In(s.location)
+	next := s.next(now)
+
+	for i := 0; i < maxWindowLookups && len(s.windows) > 0 && !s.inWindow(next); i++ {
+		start := s.nextWindowStart(next)
+		if s.cron != nil {
+			next = s.cron.Next(start.Add(-time.Second))
+		} else {
+			next = start
+		}
+	}
+
+	if s.jitter > 0 {
+		next = next.Add(rand.N(s.jitter))
+	}
+
+	return next
+}
+
+// NextActive returns the start of the next active window after now, or now
+// when it's already within one (or the schedule has no windows).
+func (s *Schedule) NextActive(now time.Time) time.Time {
+	now = now.In(s.location)
+	if len(s.windows) == 0 || s.inWindow(now) {
+		return now
+	}
+	return s.nextWindowStart(now)
+}
+
+func (s *Schedule) next(now time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(now)
+	}
+	return now.Add(s.interval)
+}
+
+func (s *Schedule) inWindow(t time.Time) bool {
+	for _, w := range s.windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) nextWindowStart(t time.Time) time.Time {
+	var earliest time.Time
+	for _, w := range s.windows {
+		start := w.nextStart(t)
+		if earliest.IsZero() || start.Before(earliest) {
+			earliest = start
+		}
+	}
+	return earliest
+}
@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+// 2026-01-05 is a monday.
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, 1, day, hour, minute, 0, 0, time.UTC)
+}
+
+func mustParseWindow(t *testing.T, days []string, from, to string) Window {
+	t.Helper()
+	w, err := ParseWindow(days, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		days     []string
+		from, to string
+		want     Window
+		wantErr  bool
+	}{
+		{name: "every day", from: "09:00", to: "17:30", want: Window{From: 9 * time.Hour, To: 17*time.Hour + 30*time.Minute}},
+		{name: "days are case insensitive", days: []string{"Mon", "FRI"}, from: "22:00", to: "02:00", want: Window{Days: []time.Weekday{time.Monday, time.Friday}, From: 22 * time.Hour, To: 2 * time.Hour}},
+		{name: "invalid day", days: []string{"monday"}, from: "09:00", to: "17:00", wantErr: true},
+		{name: "invalid time", from: "9am", to: "17:00", wantErr: true},
+		{name: "empty window", from: "09:00", to: "09:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWindow(tt.days, tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.From != tt.want.From || got.To != tt.want.To || len(got.Days) != len(tt.want.Days) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got.Days {
+				if got.Days[i] != tt.want.Days[i] {
+					t.Errorf("got %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	office := mustParseWindow(t, []string{"mon", "tue", "wed", "thu", "fri"}, "09:00", "17:00")
+	// spans midnight, so it belongs to the friday it starts at
+	fridayNight := mustParseWindow(t, []string{"fri"}, "22:00", "02:00")
+
+	tests := []struct {
+		name   string
+		window Window
+		t      time.Time
+		want   bool
+	}{
+		{name: "start is included", window: office, t: at(5, 9, 0), want: true},
+		{name: "end is excluded", window: office, t: at(5, 17, 0), want: false},
+		{name: "other day", window: office, t: at(10, 10, 0), want: false},
+		{name: "before midnight of the start day", window: fridayNight, t: at(9, 23, 0), want: true},
+		{name: "after midnight of the start day", window: fridayNight, t: at(10, 1, 0), want: true},
+		{name: "after midnight of another day", window: fridayNight, t: at(9, 1, 0), want: false},
+		{name: "before midnight of another day", window: fridayNight, t: at(10, 23, 0), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.contains(tt.t); got != tt.want {
+				t.Errorf("contains(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	office := mustParseWindow(t, []string{"mon", "tue", "wed", "thu", "fri"}, "09:00", "17:00")
+	night := mustParseWindow(t, nil, "22:00", "02:00")
+
+	tests := []struct {
+		name       string
+		expression string
+		windows    []Window
+		now        time.Time
+		want       time.Time
+	}{
+		{name: "interval", expression: "5m", now: at(5, 10, 0), want: at(5, 10, 5)},
+		{name: "cron", expression: "*/15 * * * *", now: at(5, 10, 1), want: at(5, 10, 15)},
+		{name: "interval within a window", expression: "1h", windows: []Window{office}, now: at(5, 10, 0), want: at(5, 11, 0)},
+		{name: "interval moved to the next window", expression: "1h", windows: []Window{office}, now: at(5, 16, 30), want: at(6, 9, 0)},
+		{name: "interval moved over the weekend", expression: "1h", windows: []Window{office}, now: at(9, 16, 30), want: at(12, 9, 0)},
+		{name: "cron moved to its first run in the next window", expression: "30 * * * *", windows: []Window{office}, now: at(5, 17, 10), want: at(6, 9, 30)},
+		{name: "cron within a window spanning midnight", expression: "0 * * * *", windows: []Window{night}, now: at(5, 23, 10), want: at(6, 0, 0)},
+		{name: "cron moved to a window spanning midnight", expression: "0 * * * *", windows: []Window{night}, now: at(5, 12, 10), want: at(5, 22, 0)},
+		{name: "earliest of several windows", expression: "1h", windows: []Window{office, night}, now: at(5, 16, 30), want: at(5, 22, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.expression, 0, time.UTC, tt.windows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(tt.now); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextJitter(t *testing.T) {
+	s, err := New("10m", time.Minute, time.UTC, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := at(5, 10, 0)
+	for i := 0; i < 100; i++ {
+		if next := s.Next(now); next.Before(at(5, 10, 10)) || !next.Before(at(5, 10, 11)) {
+			t.Fatalf("Next(%v) = %v, want it within the jitter", now, next)
+		}
+	}
+}
+
+func TestScheduleNextActive(t *testing.T) {
+	office := mustParseWindow(t, []string{"mon", "tue", "wed", "thu", "fri"}, "09:00", "17:00")
+
+	tests := []struct {
+		name    string
+		windows []Window
+		now     time.Time
+		want    time.Time
+	}{
+		{name: "no windows", now: at(10, 3, 0), want: at(10, 3, 0)},
+		{name: "within a window", windows: []Window{office}, now: at(5, 10, 0), want: at(5, 10, 0)},
+		{name: "before a window", windows: []Window{office}, now: at(5, 7, 0), want: at(5, 9, 0)},
+		{name: "weekend", windows: []Window{office}, now: at(10, 12, 0), want: at(12, 9, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New("1h", 0, time.UTC, tt.windows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.NextActive(tt.now); !got.Equal(tt.want) {
+				t.Errorf("NextActive(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, expression := range []string{"0s", "-5m", "not a schedule", "* * *"} {
+		if _, err := New(expression, 0, time.UTC, nil); err == nil {
+			t.Errorf("New(%q) got no error, want one", expression)
+		}
+	}
+	if _, err := New("5m", -time.Second, time.UTC, nil); err == nil {
+		t.Error("got no error for a negative jitter, want one")
+	}
+}